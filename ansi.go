@@ -0,0 +1,349 @@
+package testutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ansiPattern matches CSI sequences such as SGR color codes and cursor
+// movement, OSC sequences such as hyperlinks and titles, and other two byte
+// escape sequences
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes ANSI escape sequences from s
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// ColorKind identifies how a Color was specified
+type ColorKind int
+
+const (
+	DefaultColor ColorKind = iota // The terminal's default color
+	BasicColor                    // One of the 16 basic colors, 0-15
+	IndexedColor                  // One of the 256 indexed colors
+	RGBColor                      // A 24-bit color
+)
+
+// Color is a foreground or background color set by an SGR escape sequence
+type Color struct {
+	Kind    ColorKind
+	Index   uint8
+	R, G, B uint8
+}
+
+// The 16 basic colors; bright colors are set by SGR codes 90-97 and 100-107
+var (
+	ColorBlack         = Color{Kind: BasicColor, Index: 0}
+	ColorRed           = Color{Kind: BasicColor, Index: 1}
+	ColorGreen         = Color{Kind: BasicColor, Index: 2}
+	ColorYellow        = Color{Kind: BasicColor, Index: 3}
+	ColorBlue          = Color{Kind: BasicColor, Index: 4}
+	ColorMagenta       = Color{Kind: BasicColor, Index: 5}
+	ColorCyan          = Color{Kind: BasicColor, Index: 6}
+	ColorWhite         = Color{Kind: BasicColor, Index: 7}
+	ColorBrightBlack   = Color{Kind: BasicColor, Index: 8}
+	ColorBrightRed     = Color{Kind: BasicColor, Index: 9}
+	ColorBrightGreen   = Color{Kind: BasicColor, Index: 10}
+	ColorBrightYellow  = Color{Kind: BasicColor, Index: 11}
+	ColorBrightBlue    = Color{Kind: BasicColor, Index: 12}
+	ColorBrightMagenta = Color{Kind: BasicColor, Index: 13}
+	ColorBrightCyan    = Color{Kind: BasicColor, Index: 14}
+	ColorBrightWhite   = Color{Kind: BasicColor, Index: 15}
+)
+
+var basicColorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+}
+
+func (c Color) String() (s string) {
+	switch c.Kind {
+	case DefaultColor:
+		s = "default"
+	case BasicColor:
+		s = basicColorNames[c.Index%8]
+		if c.Index >= 8 {
+			s = "bright " + s
+		}
+	case IndexedColor:
+		s = fmt.Sprintf("color(%d)", c.Index)
+	case RGBColor:
+		s = fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return s
+}
+
+// Style is the text style set by SGR escape sequences
+type Style struct {
+	Foreground    Color
+	Background    Color
+	Bold          bool
+	Dim           bool
+	Italic        bool
+	Underline     bool
+	Blink         bool
+	Reverse       bool
+	Hidden        bool
+	Strikethrough bool
+}
+
+// StyledSpan is a run of text printed with a single style
+type StyledSpan struct {
+	Text  string
+	Style Style
+}
+
+// StyledText is text split into spans by style
+type StyledText []StyledSpan
+
+// ParseStyled parses SGR escape sequences in s into styled spans. Adjacent
+// spans with the same style are merged and other escape sequences are dropped.
+func ParseStyled(s string) (st StyledText) {
+	var style Style
+
+	for len(s) > 0 {
+		loc := ansiPattern.FindStringIndex(s)
+		if loc == nil {
+			st = st.append(s, style)
+			break
+		}
+		st = st.append(s[:loc[0]], style)
+		seq := s[loc[0]:loc[1]]
+		if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+			style = style.apply(seq[2 : len(seq)-1])
+		}
+		s = s[loc[1]:]
+	}
+	return st
+}
+
+func (st StyledText) append(text string, style Style) StyledText {
+	if text == "" {
+		return st
+	}
+	if n := len(st); n > 0 && st[n-1].Style == style {
+		st[n-1].Text += text
+		return st
+	}
+	return append(st, StyledSpan{Text: text, Style: style})
+}
+
+// apply returns the style after applying the SGR parameters in params
+func (s Style) apply(params string) Style {
+	var codes []int
+
+	for _, p := range strings.FieldsFunc(params+";", func(r rune) bool { return r == ';' || r == ':' }) {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			continue
+		}
+		codes = append(codes, n)
+	}
+	if len(codes) == 0 {
+		codes = []int{0}
+	}
+	for i := 0; i < len(codes); i++ {
+		code := codes[i]
+		switch {
+		case code == 0:
+			s = Style{}
+		case code == 1:
+			s.Bold = true
+		case code == 2:
+			s.Dim = true
+		case code == 3:
+			s.Italic = true
+		case code == 4:
+			s.Underline = true
+		case code == 5 || code == 6:
+			s.Blink = true
+		case code == 7:
+			s.Reverse = true
+		case code == 8:
+			s.Hidden = true
+		case code == 9:
+			s.Strikethrough = true
+		case code == 21 || code == 22:
+			s.Bold, s.Dim = false, false
+		case code == 23:
+			s.Italic = false
+		case code == 24:
+			s.Underline = false
+		case code == 25:
+			s.Blink = false
+		case code == 27:
+			s.Reverse = false
+		case code == 28:
+			s.Hidden = false
+		case code == 29:
+			s.Strikethrough = false
+		case code >= 30 && code <= 37:
+			s.Foreground = Color{Kind: BasicColor, Index: uint8(code - 30)}
+		case code == 38:
+			s.Foreground, i = extendedColor(codes, i)
+		case code == 39:
+			s.Foreground = Color{}
+		case code >= 40 && code <= 47:
+			s.Background = Color{Kind: BasicColor, Index: uint8(code - 40)}
+		case code == 48:
+			s.Background, i = extendedColor(codes, i)
+		case code == 49:
+			s.Background = Color{}
+		case code >= 90 && code <= 97:
+			s.Foreground = Color{Kind: BasicColor, Index: uint8(code - 90 + 8)}
+		case code >= 100 && code <= 107:
+			s.Background = Color{Kind: BasicColor, Index: uint8(code - 100 + 8)}
+		}
+	}
+	return s
+}
+
+// extendedColor parses a 38 or 48 color at codes[i] in either the 256 color
+// form "5;n" or the 24-bit form "2;r;g;b", returning the index of the last
+// code consumed
+func extendedColor(codes []int, i int) (c Color, last int) {
+	last = i
+	if i+1 >= len(codes) {
+		goto end
+	}
+	switch codes[i+1] {
+	case 5:
+		if i+2 < len(codes) {
+			c = Color{Kind: IndexedColor, Index: uint8(codes[i+2])}
+			last = i + 2
+		}
+	case 2:
+		if i+4 < len(codes) {
+			c = Color{Kind: RGBColor, R: uint8(codes[i+2]), G: uint8(codes[i+3]), B: uint8(codes[i+4])}
+			last = i + 4
+		}
+	}
+end:
+	return c, last
+}
+
+// Plain returns the text without any styling
+func (st StyledText) Plain() string {
+	var sb strings.Builder
+	for _, span := range st {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+// Has returns true if text appears with every byte of it printed in a style
+// for which match returns true, e.g.:
+//
+//	w.StyledStdout().Has("FAILED", func(s Style) bool {
+//		return s.Foreground == ColorRed
+//	})
+func (st StyledText) Has(text string, match func(Style) bool) bool {
+	var styles []Style
+
+	plain := st.Plain()
+	if text == "" {
+		return false
+	}
+	for _, span := range st {
+		for range len(span.Text) {
+			styles = append(styles, span.Style)
+		}
+	}
+	for start := 0; ; {
+		i := strings.Index(plain[start:], text)
+		if i < 0 {
+			return false
+		}
+		i += start
+		if allStylesMatch(styles[i:i+len(text)], match) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func allStylesMatch(styles []Style, match func(Style) bool) bool {
+	for _, s := range styles {
+		if !match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// StyleOf returns the style text is first printed with, if it appears
+func (st StyledText) StyleOf(text string) (style Style, ok bool) {
+	var offset int
+
+	i := strings.Index(st.Plain(), text)
+	if i < 0 {
+		goto end
+	}
+	for _, span := range st {
+		if i < offset+len(span.Text) {
+			style, ok = span.Style, true
+			break
+		}
+		offset += len(span.Text)
+	}
+end:
+	return style, ok
+}
+
+// GetPlainStdout returns stdout with ANSI escape sequences removed
+func (w *BufferedWriter) GetPlainStdout() string {
+	return StripANSI(w.GetStdout())
+}
+
+// GetPlainStderr returns stderr with ANSI escape sequences removed
+func (w *BufferedWriter) GetPlainStderr() string {
+	return StripANSI(w.GetStderr())
+}
+
+// GetPlainStdoutLines returns stdout split into lines like GetStdoutLines, with
+// ANSI escape sequences removed before empty lines are dropped
+func (w *BufferedWriter) GetPlainStdoutLines() []string {
+	return nonEmptyLines(w.GetPlainStdout())
+}
+
+// GetPlainStderrLines returns stderr split into lines like GetStderrLines, with
+// ANSI escape sequences removed before empty lines are dropped
+func (w *BufferedWriter) GetPlainStderrLines() []string {
+	return nonEmptyLines(w.GetPlainStderr())
+}
+
+// nonEmptyLines splits content into lines, excluding empty lines, the way
+// GetStdoutLines does
+func nonEmptyLines(content string) (lines []string) {
+	lines = []string{}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ContainsPlainStdout returns true if stdout contains s once ANSI escape
+// sequences are removed
+func (w *BufferedWriter) ContainsPlainStdout(s string) bool {
+	return strings.Contains(w.GetPlainStdout(), s)
+}
+
+// ContainsPlainStderr returns true if stderr contains s once ANSI escape
+// sequences are removed
+func (w *BufferedWriter) ContainsPlainStderr(s string) bool {
+	return strings.Contains(w.GetPlainStderr(), s)
+}
+
+// StyledStdout returns stdout parsed into styled spans
+func (w *BufferedWriter) StyledStdout() StyledText {
+	return ParseStyled(w.GetStdout())
+}
+
+// StyledStderr returns stderr parsed into styled spans
+func (w *BufferedWriter) StyledStderr() StyledText {
+	return ParseStyled(w.GetStderr())
+}
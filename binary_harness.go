@@ -0,0 +1,306 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// Binaries built by BuildBinary, cached by package and build flags for the
+// life of the test process
+var (
+	builtBinaries   = make(map[string]dt.Filepath)
+	builtBinaryDir  dt.DirPath
+	builtBinariesMu sync.Mutex
+)
+
+// BuildBinary builds the main package pkg once per test run and returns the
+// path to the binary. pkg is anything `go build` accepts, such as "./cmd/app"
+// or a testdata directory. The binary is built with -race and -cover when the
+// test binary itself was. Binaries are shared by every test so they outlive
+// any one test's temp directory; run the tests with RunWithBinaryCleanup to
+// remove them when the run ends.
+func BuildBinary(t *testing.T, pkg string) (bin dt.Filepath) {
+	var key string
+	var buildArgs []string
+	var out []byte
+	var ok bool
+	var err error
+
+	t.Helper()
+	buildArgs = binaryBuildFlags()
+	key, err = filepath.Abs(pkg)
+	if err != nil {
+		key = pkg
+	}
+	key += " " + strings.Join(buildArgs, " ")
+
+	builtBinariesMu.Lock()
+	defer builtBinariesMu.Unlock()
+
+	bin, ok = builtBinaries[key]
+	if ok {
+		goto end
+	}
+	if builtBinaryDir == "" {
+		builtBinaryDir, err = dt.MkdirTemp(dt.TempDir(), "testutil-bin-")
+		if err != nil {
+			t.Fatalf("Failed to create directory for test binaries: %v", err)
+		}
+	}
+	bin = dt.FilepathJoin(builtBinaryDir, fmt.Sprintf("bin%d%s", len(builtBinaries), exeSuffix()))
+	buildArgs = append([]string{"build", "-o", string(bin)}, buildArgs...)
+	out, err = exec.Command("go", append(buildArgs, pkg)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to build %s: %v\n%s", pkg, err, out)
+	}
+	builtBinaries[key] = bin
+
+end:
+	return bin
+}
+
+// RemoveBuiltBinaries deletes the binaries built by BuildBinary; see
+// RunWithBinaryCleanup
+func RemoveBuiltBinaries() (err error) {
+	builtBinariesMu.Lock()
+	defer builtBinariesMu.Unlock()
+	if builtBinaryDir == "" {
+		goto end
+	}
+	err = builtBinaryDir.RemoveAll()
+	builtBinaries = make(map[string]dt.Filepath)
+	builtBinaryDir = ""
+end:
+	return err
+}
+
+// RunWithBinaryCleanup runs the tests and then removes the binaries built by
+// BuildBinary so their temp directory is not left behind. Use it from
+// TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(testutil.RunWithBinaryCleanup(m))
+//	}
+func RunWithBinaryCleanup(m *testing.M) (code int) {
+	code = m.Run()
+	err := RemoveBuiltBinaries()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to remove test binaries: %v\n", err)
+	}
+	return code
+}
+
+// binaryBuildFlags returns the go build flags that match how the running test
+// binary was built
+func binaryBuildFlags() (args []string) {
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		for _, s := range info.Settings {
+			if s.Key == "-race" && s.Value == "true" {
+				args = append(args, "-race")
+			}
+		}
+	}
+	if testing.CoverMode() != "" {
+		args = append(args, "-cover", "-covermode="+testing.CoverMode())
+	}
+	return args
+}
+
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// coverDir returns the directory coverage data from subprocesses should be
+// written to so it is merged with the test's own coverage
+func coverDir(t *testing.T) (dir string) {
+	f := flag.Lookup("test.gocoverdir")
+	if f != nil {
+		dir = f.Value.String()
+	}
+	if dir == "" {
+		dir = os.Getenv("GOCOVERDIR")
+	}
+	if dir == "" && testing.CoverMode() != "" {
+		// Avoids the "GOCOVERDIR not set" warning on the binary's stderr
+		dir = t.TempDir()
+	}
+	return dir
+}
+
+// ExecArgs configures RunBinaryWithArgs and StartBinary. All fields are
+// optional.
+type ExecArgs struct {
+	Args []string
+	// Env is added to the test process's environment
+	Env     map[string]string
+	Stdin   io.Reader
+	Dir     dt.DirPath
+	Context context.Context
+	// Writer captures stdout and stderr; one is created if nil
+	Writer *BufferedWriter
+}
+
+// RunBinary runs bin with the given args and waits for it to exit
+func RunBinary(t *testing.T, bin dt.Filepath, args ...string) *CLIResult {
+	t.Helper()
+	return RunBinaryWithArgs(t, bin, &ExecArgs{Args: args})
+}
+
+// RunBinaryWithArgs runs bin and waits for it to exit
+func RunBinaryWithArgs(t *testing.T, bin dt.Filepath, args *ExecArgs) *CLIResult {
+	t.Helper()
+	return StartBinary(t, bin, args).Wait()
+}
+
+// BinaryProcess is a running binary started by StartBinary
+type BinaryProcess struct {
+	Cmd      *exec.Cmd
+	result   *CLIResult
+	stdin    io.Reader
+	waitOnce sync.Once
+	waited   chan struct{}
+}
+
+// StartBinary starts bin without waiting for it to exit, e.g. so a test can
+// send it a signal. Stdout and stderr are captured by the result's Writer
+// while the process runs. If the test ends without calling Wait, the process
+// is killed and reaped so it does not outlive the test.
+func StartBinary(t *testing.T, bin dt.Filepath, args *ExecArgs) *BinaryProcess {
+	var cmd *exec.Cmd
+	var dir string
+	var stdinR, stdinW *os.File
+	var ctx context.Context
+	var w *BufferedWriter
+	var err error
+
+	t.Helper()
+	if args == nil {
+		args = &ExecArgs{}
+	}
+	// args is left unchanged so it can be reused to start another process
+	ctx = args.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	w = args.Writer
+	if w == nil {
+		w = NewBufferedWriter()
+	}
+	result := &CLIResult{
+		t:          t,
+		Args:       args.Args,
+		Writer:     w,
+		LogHandler: NewBufferedLogHandler(),
+	}
+
+	cmd = exec.CommandContext(ctx, string(bin), args.Args...)
+	cmd.Dir = string(args.Dir)
+	cmd.Stdin = args.Stdin
+	if _, ok := args.Stdin.(inputStopper); ok {
+		// exec.Cmd.Wait waits for its own stdin copy to finish, which would
+		// block until the input ends, so the input is copied here instead and
+		// stopped once the process exits
+		stdinR, stdinW, err = os.Pipe()
+		if err != nil {
+			t.Fatalf("Failed to create stdin pipe: %v", err)
+		}
+		cmd.Stdin = stdinR
+	}
+	cmd.Stdout = result.Writer.Writer()
+	cmd.Stderr = result.Writer.ErrWriter()
+	cmd.Env = os.Environ()
+	for key, value := range args.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	dir = coverDir(t)
+	if dir != "" {
+		cmd.Env = append(cmd.Env, "GOCOVERDIR="+dir)
+	}
+
+	err = cmd.Start()
+	if stdinR != nil {
+		_ = stdinR.Close()
+	}
+	if err != nil {
+		if stdinW != nil {
+			_ = stdinW.Close()
+		}
+		t.Fatalf("Failed to start %s: %v", bin, err)
+	}
+	if stdinW != nil {
+		go func() {
+			_, _ = io.Copy(stdinW, args.Stdin)
+			_ = stdinW.Close()
+		}()
+	}
+	p := &BinaryProcess{
+		Cmd:    cmd,
+		result: result,
+		stdin:  args.Stdin,
+		waited: make(chan struct{}),
+	}
+	t.Cleanup(p.kill)
+	return p
+}
+
+// kill kills and reaps the process unless Wait has already reaped it
+func (p *BinaryProcess) kill() {
+	select {
+	case <-p.waited:
+		return
+	default:
+	}
+	_ = p.Cmd.Process.Kill()
+	p.Wait()
+}
+
+// Output returns the writer capturing the process's stdout and stderr
+func (p *BinaryProcess) Output() *BufferedWriter {
+	return p.result.Writer
+}
+
+// Signal sends sig to the process
+func (p *BinaryProcess) Signal(sig os.Signal) error {
+	return p.Cmd.Process.Signal(sig)
+}
+
+// Wait waits for the process to exit and returns its result. A process killed
+// by a signal has an exit code of -1. It is safe to call more than once.
+func (p *BinaryProcess) Wait() *CLIResult {
+	p.waitOnce.Do(p.wait)
+	return p.result
+}
+
+func (p *BinaryProcess) wait() {
+	var exitErr *exec.ExitError
+
+	defer close(p.waited)
+	err := p.Cmd.Wait()
+	stopInput(p.stdin)
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		p.result.Err = err
+		p.result.ExitCode = exitErr.ExitCode()
+	default:
+		p.result.Err = err
+		p.result.ExitCode = -1
+	}
+}
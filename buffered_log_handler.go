@@ -5,15 +5,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 )
 
 // BufferedLogHandler implements slog.Handler and captures logs in a buffer
 type BufferedLogHandler struct {
 	//opts   slog.HandlerOptions
-	buffer *bytes.Buffer
-	mu     sync.Mutex
+	buffer  *bytes.Buffer
+	records []capturedRecord
+	guard   *testGuard
+	late    []LateLogCall
+	mu      sync.Mutex
 }
 
 // NewBufferedLogHandler creates a new BufferedLogHandler
@@ -29,13 +35,24 @@ func (h *BufferedLogHandler) Enabled(context.Context, slog.Level) bool {
 }
 
 // Handle implements slog.Handler
-func (h *BufferedLogHandler) Handle(_ context.Context, r slog.Record) (err error) {
+func (h *BufferedLogHandler) Handle(ctx context.Context, r slog.Record) (err error) {
+	return h.handle(r, testFromContext(ctx))
+}
+
+// handle captures the record, tagging it with the name of the test that
+// logged it, if known
+func (h *BufferedLogHandler) handle(r slog.Record, test string) (err error) {
 	var data []byte
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.guard != nil && h.guard.ended() {
+		h.late = append(h.late, recordLateLogCall(h.guard.test, r))
+	}
+
 	entry := NewLogEntry(r)
+	entry.Test = test
 
 	// Add attributes
 	r.Attrs(func(attr slog.Attr) bool {
@@ -50,20 +67,108 @@ func (h *BufferedLogHandler) Handle(_ context.Context, r slog.Record) (err error
 	}
 	h.buffer.Write(data)
 	h.buffer.WriteByte('\n')
+	h.records = append(h.records, capturedRecord{
+		record: r.Clone(),
+		entry:  *entry,
+		seq:    nextCaptureSeq(),
+	})
 end:
 	return err
 }
 
+// WithAttrs implements slog.Handler. Records logged through the returned
+// handler are captured by h with attrs added.
+func (h *BufferedLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return newBoundLogHandler(h, "").WithAttrs(attrs)
+}
+
+// WithGroup implements slog.Handler. Records logged through the returned
+// handler are captured by h with their attrs in the named group.
+func (h *BufferedLogHandler) WithGroup(name string) slog.Handler {
+	return newBoundLogHandler(h, "").WithGroup(name)
+}
+
+// logGroup is a group opened by WithGroup and the attrs added within it. The
+// first group of a boundLogHandler is unnamed and holds its top-level attrs.
+type logGroup struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// boundLogHandler implements slog.Handler by capturing into a parent
+// BufferedLogHandler with the attrs and groups from WithAttrs and WithGroup
+// applied, and optionally with every record tagged with a test name
+type boundLogHandler struct {
+	parent *BufferedLogHandler
+	test   string
+	groups []logGroup
+}
+
+func newBoundLogHandler(parent *BufferedLogHandler, test string) *boundLogHandler {
+	return &boundLogHandler{
+		parent: parent,
+		test:   test,
+		groups: []logGroup{{}},
+	}
+}
+
+// Enabled implements slog.Handler
+func (h *boundLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.parent.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *boundLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	test := testFromContext(ctx)
+	if test == "" {
+		test = h.test
+	}
+	return h.parent.handle(h.bind(r), test)
+}
+
+// bind returns r with the handler's attrs added and its own attrs nested in
+// the open groups. Empty groups are dropped, as slog handlers do.
+func (h *boundLogHandler) bind(r slog.Record) slog.Record {
+	var attrs []slog.Attr
+
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		inner := append(slices.Clone(h.groups[i].attrs), attrs...)
+		switch {
+		case i == 0:
+			attrs = inner
+		case len(inner) == 0:
+			attrs = nil
+		default:
+			attrs = []slog.Attr{{Key: h.groups[i].name, Value: slog.GroupValue(inner...)}}
+		}
+	}
+	bound := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	bound.AddAttrs(attrs...)
+	return bound
+}
+
 // WithAttrs implements slog.Handler
-func (h *BufferedLogHandler) WithAttrs([]slog.Attr) slog.Handler {
-	// For simplicity in testing, we don't need to implement this properly
-	return h
+func (h *boundLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	groups := slices.Clone(h.groups)
+	last := &groups[len(groups)-1]
+	last.attrs = append(slices.Clip(last.attrs), attrs...)
+	return &boundLogHandler{parent: h.parent, test: h.test, groups: groups}
 }
 
 // WithGroup implements slog.Handler
-func (h *BufferedLogHandler) WithGroup(string) slog.Handler {
-	// For simplicity in testing, we don't need to implement this properly
-	return h
+func (h *boundLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(slices.Clip(h.groups), logGroup{name: name})
+	return &boundLogHandler{parent: h.parent, test: h.test, groups: groups}
 }
 
 // Buffer returns the underlying buffer
@@ -85,6 +190,7 @@ func (h *BufferedLogHandler) Reset() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.buffer.Reset()
+	h.records = nil
 }
 
 // Contains returns true if the buffer contains the specified substring
@@ -159,3 +265,80 @@ func (h *BufferedLogHandler) GetLogEntriesByLevel(level slog.Level) (entries []L
 end:
 	return entries, err
 }
+
+// Records returns a copy of the captured slog records with their original
+// typed attribute values
+func (h *BufferedLogHandler) Records() (records []slog.Record) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	records = make([]slog.Record, len(h.records))
+	for i, cr := range h.records {
+		records[i] = cr.record
+	}
+	return records
+}
+
+// Timeline returns the captured entries on the log stream so they can be
+// merged with a BufferedWriter's timeline using MergeTimelines
+func (h *BufferedLogHandler) Timeline() (tl Timeline) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	tl = make(Timeline, len(h.records))
+	for i, cr := range h.records {
+		entry := cr.entry
+		entry.OmitDateTime = true
+		tl[i] = TimelineEntry{
+			Seq:    cr.seq,
+			Stream: LogStream,
+			Text:   entry.String() + "\n",
+		}
+	}
+	return tl
+}
+
+// CapturedText returns the message and each attribute value of every captured
+// log entry, with locations such as "log entry 2 message" or "log entry 2 attr
+// token"
+func (h *BufferedLogHandler) CapturedText() (texts []CapturedText) {
+	var entries LogEntries
+	var key, value string
+	var found bool
+
+	h.mu.Lock()
+	entries = h.entries()
+	h.mu.Unlock()
+
+	for i, entry := range entries {
+		texts = append(texts, CapturedText{
+			Location: fmt.Sprintf("log entry %d message", i+1),
+			Text:     entry.Message,
+		})
+		for _, attr := range entry.Attrs {
+			key, value, found = strings.Cut(attr, "=")
+			if !found {
+				key, value = "", attr
+			}
+			texts = append(texts, CapturedText{
+				Location: fmt.Sprintf("log entry %d attr %s", i+1, key),
+				Text:     value,
+			})
+		}
+	}
+	return texts
+}
+
+// entries returns the captured log entries. Caller must hold h.mu.
+func (h *BufferedLogHandler) entries() (entries LogEntries) {
+	entries = make(LogEntries, len(h.records))
+	for i, cr := range h.records {
+		entries[i] = cr.entry
+	}
+	return entries
+}
+
+// capturedRecord is a handled record along with the entry written for it
+type capturedRecord struct {
+	record slog.Record
+	entry  LogEntry
+	seq    uint64
+}
@@ -4,60 +4,83 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"sync"
+	"testing"
 
 	"github.com/mikeschinkel/go-cliutil"
 )
 
 // BufferedWriter implements cliutil.Writer and captures all output in buffers for testing
 type BufferedWriter struct {
-	stdBuf     *bytes.Buffer
-	errBuf     *bytes.Buffer
-	mu         sync.RWMutex
-	quiet      bool
-	verbosity  int
-	useLevel   int
-	loudWriter cliutil.Writer
-	v2Writer   cliutil.Writer
-	v3Writer   cliutil.Writer
+	out      *capturedOutput
+	settings *writerSettings
+	level    int
+	loud     bool
+	mu       sync.Mutex
+	loudW    *BufferedWriter
+	levels   map[int]*BufferedWriter
 }
 
 // Verify BufferedWriter implements cliutil.Writer interface
 var _ cliutil.Writer = (*BufferedWriter)(nil)
 
+// writerSettings are shared by a BufferedWriter and the Loud and V(n) writers
+// derived from it, so changing them affects every level the same way
+type writerSettings struct {
+	t            *testing.T
+	quiet        bool
+	verbosity    int
+	minVerbosity int
+	maxVerbosity int
+	mu           sync.RWMutex
+}
+
 // NewBufferedWriter creates a new BufferedWriter with default settings
 func NewBufferedWriter() *BufferedWriter {
+	return newBufferedWriter(nil)
+}
+
+// NewTestBufferedWriter creates a new BufferedWriter that reports invalid
+// verbosity settings by failing t instead of panicking
+func NewTestBufferedWriter(t *testing.T) *BufferedWriter {
+	return newBufferedWriter(t)
+}
+
+func newBufferedWriter(t *testing.T) *BufferedWriter {
 	return &BufferedWriter{
-		stdBuf:    &bytes.Buffer{},
-		errBuf:    &bytes.Buffer{},
-		quiet:     false,
-		verbosity: 3, // Default to max verbosity for testing
-		useLevel:  1, // Default level
+		out: &capturedOutput{},
+		settings: &writerSettings{
+			t:            t,
+			quiet:        false,
+			verbosity:    3, // Default to max verbosity for testing
+			minVerbosity: 1,
+			maxVerbosity: 3,
+		},
+		level: 1, // Default level
 	}
 }
 
 // Printf writes formatted output to stdout buffer
 func (w *BufferedWriter) Printf(format string, args ...any) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	w.settings.mu.RLock()
+	quiet, verbosity := w.settings.quiet, w.settings.verbosity
+	w.settings.mu.RUnlock()
 
-	if w.quiet {
+	if quiet && !w.loud {
 		return
 	}
-	if w.verbosity < w.useLevel {
+	if verbosity < w.level {
 		return
 	}
 
 	formatted := fmt.Sprintf(format, args...)
-	w.stdBuf.WriteString(formatted)
+	w.out.writeCall(StdoutStream, format, args, formatted)
 }
 
 // Errorf writes formatted error output to doterr buffer
 func (w *BufferedWriter) Errorf(format string, args ...any) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
 	// Process error arguments to flatten newlines (same as cliWriter)
 	processedArgs := make([]any, len(args))
 	for i, arg := range args {
@@ -69,7 +92,7 @@ func (w *BufferedWriter) Errorf(format string, args ...any) {
 	}
 
 	formatted := fmt.Sprintf(format, processedArgs...)
-	w.errBuf.WriteString(formatted)
+	w.out.writeCall(StderrStream, format, args, formatted)
 }
 
 // Loud returns a Writer that ignores the quiet setting
@@ -77,133 +100,176 @@ func (w *BufferedWriter) Loud() cliutil.Writer {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.loudWriter != nil {
-		return w.loudWriter
+	if w.loudW == nil {
+		w.loudW = w.child(w.level, true)
 	}
-
-	w.loudWriter = &BufferedWriter{
-		stdBuf:    w.stdBuf, // Share the same buffers
-		errBuf:    w.errBuf,
-		quiet:     false, // Always loud
-		verbosity: w.verbosity,
-		useLevel:  w.useLevel,
-	}
-	return w.loudWriter
+	return w.loudW
 }
 
-// V2 returns a Writer for verbosity level 2
-func (w *BufferedWriter) V2() cliutil.Writer {
+// V returns the Writer for verbosity level n, which only writes when the
+// verbosity is at least n. The same Writer is returned for every call with
+// the same n. A level outside the verbosity range fails the test, or panics
+// if the writer was not created by NewTestBufferedWriter.
+func (w *BufferedWriter) V(n int) *BufferedWriter {
+	w.settings.checkVerbosity("level", n)
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.v2Writer != nil {
-		return w.v2Writer
+	if n == w.level {
+		return w
 	}
-
-	w.v2Writer = &BufferedWriter{
-		stdBuf:    w.stdBuf, // Share the same buffers
-		errBuf:    w.errBuf,
-		quiet:     w.quiet,
-		verbosity: w.verbosity,
-		useLevel:  2, // Level 2
+	child, ok := w.levels[n]
+	if !ok {
+		child = w.child(n, w.loud)
+		if w.levels == nil {
+			w.levels = make(map[int]*BufferedWriter)
+		}
+		w.levels[n] = child
 	}
-	return w.v2Writer
+	return child
+}
+
+// V2 returns a Writer for verbosity level 2
+func (w *BufferedWriter) V2() cliutil.Writer {
+	return w.V(2)
 }
 
 // V3 returns a Writer for verbosity level 3
 func (w *BufferedWriter) V3() cliutil.Writer {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if w.v3Writer != nil {
-		return w.v3Writer
-	}
+	return w.V(3)
+}
 
-	w.v3Writer = &BufferedWriter{
-		stdBuf:    w.stdBuf, // Share the same buffers
-		errBuf:    w.errBuf,
-		quiet:     w.quiet,
-		verbosity: w.verbosity,
-		useLevel:  3, // Level 3
+// child returns a writer sharing w's buffers and settings
+func (w *BufferedWriter) child(level int, loud bool) *BufferedWriter {
+	return &BufferedWriter{
+		out:      w.out, // Share the same buffers
+		settings: w.settings,
+		level:    level,
+		loud:     loud,
 	}
-	return w.v3Writer
 }
 
 // Testing helper methods
 
 // GetStdout returns the current stdout buffer contents
 func (w *BufferedWriter) GetStdout() string {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return w.stdBuf.String()
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return w.out.stdBuf.String()
 }
 
 // GetStderr returns the current doterr buffer contents
 func (w *BufferedWriter) GetStderr() string {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return w.errBuf.String()
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return w.out.errBuf.String()
 }
 
 // GetAllOutput returns both stdout and doterr combined
 func (w *BufferedWriter) GetAllOutput() string {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return w.stdBuf.String() + w.errBuf.String()
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return w.out.stdBuf.String() + w.out.errBuf.String()
 }
 
 // ContainsStdout returns true if stdout buffer contains the specified substring
 func (w *BufferedWriter) ContainsStdout(s string) bool {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return strings.Contains(w.stdBuf.String(), s)
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return strings.Contains(w.out.stdBuf.String(), s)
 }
 
 // ContainsStderr returns true if doterr buffer contains the specified substring
 func (w *BufferedWriter) ContainsStderr(s string) bool {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return strings.Contains(w.errBuf.String(), s)
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return strings.Contains(w.out.errBuf.String(), s)
 }
 
 // ContainsOutput returns true if either buffer contains the specified substring
 func (w *BufferedWriter) ContainsOutput(s string) bool {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return strings.Contains(w.stdBuf.String(), s) || strings.Contains(w.errBuf.String(), s)
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return strings.Contains(w.out.stdBuf.String(), s) || strings.Contains(w.out.errBuf.String(), s)
 }
 
-// Reset clears both stdout and doterr buffers
+// Reset clears both stdout and doterr buffers along with the timeline
 func (w *BufferedWriter) Reset() {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.stdBuf.Reset()
-	w.errBuf.Reset()
+	w.out.mu.Lock()
+	defer w.out.mu.Unlock()
+	w.out.stdBuf.Reset()
+	w.out.errBuf.Reset()
+	w.out.timeline = nil
+	w.out.calls = nil
 }
 
 // SetQuiet sets the quiet mode (suppresses all Printf output)
 func (w *BufferedWriter) SetQuiet(quiet bool) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.quiet = quiet
+	w.settings.mu.Lock()
+	defer w.settings.mu.Unlock()
+	w.settings.quiet = quiet
 }
 
-// SetVerbosity sets the verbosity level (1-3)
+// SetVerbosity sets the verbosity level, which must be within the verbosity
+// range; 1-3 unless changed with SetVerbosityRange
 func (w *BufferedWriter) SetVerbosity(verbosity int) {
-	if verbosity < 1 || verbosity > 3 {
-		panic(fmt.Sprintf("Invalid verbosity for BufferedWriter; must be between 1-3; got %d", verbosity))
+	if !w.settings.checkVerbosity("verbosity", verbosity) {
+		return
 	}
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.verbosity = verbosity
+	w.settings.mu.Lock()
+	defer w.settings.mu.Unlock()
+	w.settings.verbosity = verbosity
+}
+
+// Verbosity returns the current verbosity level
+func (w *BufferedWriter) Verbosity() int {
+	w.settings.mu.RLock()
+	defer w.settings.mu.RUnlock()
+	return w.settings.verbosity
+}
+
+// SetVerbosityRange sets the range of valid verbosity levels, e.g. 0 for
+// silent through 5 for trace. The current verbosity is clamped to the range.
+func (w *BufferedWriter) SetVerbosityRange(minVerbosity, maxVerbosity int) {
+	if minVerbosity > maxVerbosity {
+		w.settings.fail(fmt.Sprintf("Invalid verbosity range for BufferedWriter; %d is greater than %d", minVerbosity, maxVerbosity))
+		return
+	}
+	w.settings.mu.Lock()
+	defer w.settings.mu.Unlock()
+	w.settings.minVerbosity = minVerbosity
+	w.settings.maxVerbosity = maxVerbosity
+	w.settings.verbosity = min(max(w.settings.verbosity, minVerbosity), maxVerbosity)
+}
+
+// checkVerbosity reports a verbosity or level outside the configured range
+func (s *writerSettings) checkVerbosity(what string, n int) (ok bool) {
+	s.mu.RLock()
+	minV, maxV := s.minVerbosity, s.maxVerbosity
+	s.mu.RUnlock()
+	if n >= minV && n <= maxV {
+		return true
+	}
+	s.fail(fmt.Sprintf("Invalid %s for BufferedWriter; must be between %d-%d; got %d", what, minV, maxV, n))
+	return false
+}
+
+// fail fails the test if there is one and otherwise panics
+func (s *writerSettings) fail(msg string) {
+	if s.t == nil {
+		panic(msg)
+	}
+	s.t.Helper()
+	s.t.Error(msg)
 }
 
 // GetStdoutLines returns stdout content split into lines (excluding empty lines)
 func (w *BufferedWriter) GetStdoutLines() []string {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
 
-	content := w.stdBuf.String()
+	content := w.out.stdBuf.String()
 	if content == "" {
 		return []string{}
 	}
@@ -220,10 +286,10 @@ func (w *BufferedWriter) GetStdoutLines() []string {
 
 // GetStderrLines returns doterr content split into lines (excluding empty lines)
 func (w *BufferedWriter) GetStderrLines() []string {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
 
-	content := w.errBuf.String()
+	content := w.out.errBuf.String()
 	if content == "" {
 		return []string{}
 	}
@@ -248,10 +314,102 @@ func (w *BufferedWriter) CountStderrLines() int {
 	return len(w.GetStderrLines())
 }
 
+// CapturedText returns each non-empty stdout and stderr line along with its
+// stream and 1-based line number
+func (w *BufferedWriter) CapturedText() (texts []CapturedText) {
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+
+	texts = appendCapturedLines(texts, StdoutStream, w.out.stdBuf.String())
+	texts = appendCapturedLines(texts, StderrStream, w.out.errBuf.String())
+	return texts
+}
+
+func appendCapturedLines(texts []CapturedText, stream, content string) []CapturedText {
+	for i, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		texts = append(texts, CapturedText{
+			Location: fmt.Sprintf("%s line %d", stream, i+1),
+			Text:     line,
+		})
+	}
+	return texts
+}
+
+// Writer returns an io.Writer that captures to stdout
 func (w *BufferedWriter) Writer() io.Writer {
-	return w.stdBuf
+	return streamWriter{out: w.out, stream: StdoutStream}
 }
 
+// ErrWriter returns an io.Writer that captures to stderr
 func (w *BufferedWriter) ErrWriter() io.Writer {
-	return w.errBuf
+	return streamWriter{out: w.out, stream: StderrStream}
+}
+
+// Timeline returns every captured write to stdout and stderr in the order
+// they were made
+func (w *BufferedWriter) Timeline() Timeline {
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return slices.Clone(w.out.timeline)
+}
+
+// capturedOutput holds the buffers shared by a BufferedWriter and the Loud, V2
+// and V3 writers derived from it
+type capturedOutput struct {
+	stdBuf   bytes.Buffer
+	errBuf   bytes.Buffer
+	timeline Timeline
+	calls    []FormatCall
+	mu       sync.RWMutex
+}
+
+func (o *capturedOutput) write(stream, text string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.writeLocked(stream, text)
+}
+
+// writeCall writes text along with the call that formatted it
+func (o *capturedOutput) writeCall(stream, format string, args []any, text string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	seq := o.writeLocked(stream, text)
+	o.calls = append(o.calls, FormatCall{
+		Seq:    seq,
+		Stream: stream,
+		Format: format,
+		Args:   slices.Clone(args),
+		Text:   text,
+	})
+}
+
+// writeLocked writes text and returns its sequence number. Caller must hold o.mu.
+func (o *capturedOutput) writeLocked(stream, text string) (seq uint64) {
+	seq = nextCaptureSeq()
+	switch stream {
+	case StdoutStream:
+		o.stdBuf.WriteString(text)
+	case StderrStream:
+		o.errBuf.WriteString(text)
+	}
+	o.timeline = append(o.timeline, TimelineEntry{
+		Seq:    seq,
+		Stream: stream,
+		Text:   text,
+	})
+	return seq
+}
+
+// streamWriter implements io.Writer for one stream of a capturedOutput
+type streamWriter struct {
+	out    *capturedOutput
+	stream string
+}
+
+func (sw streamWriter) Write(p []byte) (int, error) {
+	sw.out.write(sw.stream, string(p))
+	return len(p), nil
 }
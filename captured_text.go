@@ -0,0 +1,14 @@
+package testutil
+
+// CapturedText is a single piece of captured output along with a description
+// of where it was captured, e.g. "stdout line 3" or "log entry 2 attr token"
+type CapturedText struct {
+	Location string
+	Text     string
+}
+
+// CapturedTextSource is implemented by types that capture output for tests,
+// such as BufferedWriter and BufferedLogHandler
+type CapturedTextSource interface {
+	CapturedText() []CapturedText
+}
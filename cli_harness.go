@@ -0,0 +1,254 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+)
+
+// ExitCoder is implemented by errors that carry their own process exit code
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// CLIRunArgs configures RunCLIWithArgs. All fields are optional.
+type CLIRunArgs struct {
+	Args []string
+	// Env is set with t.Setenv so it lasts for the rest of the test
+	Env map[string]string
+	// Stdin replaces os.Stdin while the command runs
+	Stdin io.Reader
+	// Dir is set with t.Chdir so it lasts for the rest of the test
+	Dir dt.DirPath
+	// SetVerbosity applies Verbosity, which may then be 0; otherwise the
+	// writer's verbosity is left as is. Verbosity must be within the writer's
+	// range, so pass a Writer with SetVerbosityRange for levels outside 1-3.
+	SetVerbosity bool
+	Verbosity    int
+	Quiet        bool
+	Writer       *BufferedWriter
+	LogHandler   *BufferedLogHandler
+	Context      context.Context
+	Config       cliutil.Config
+	Options      cliutil.Options
+}
+
+// RunCLI runs a go-cliutil command in-process with the given args, capturing
+// its output with a BufferedWriter and its logs with a BufferedLogHandler
+func RunCLI(t *testing.T, cmd cliutil.Command, args ...string) *CLIResult {
+	t.Helper()
+	return RunCLIWithArgs(t, cmd, &CLIRunArgs{Args: args})
+}
+
+// RunCLIWithArgs runs a go-cliutil command in-process the same way the
+// cliutil.CmdRunner would: parsing its flags, assigning its positional args
+// and then calling its Handle method. The command's writer is also installed
+// as the cliutil package writer while it runs.
+//
+// Because the cliutil package writer and os.Stdin are process-wide, tests that
+// run commands must not call t.Parallel.
+func RunCLIWithArgs(t *testing.T, cmd cliutil.Command, args *CLIRunArgs) (result *CLIResult) {
+	var handler cliutil.CommandHandler
+	var remaining []string
+	var restoreStdin func()
+	var prevWriter cliutil.Writer
+	var ctx context.Context
+	var ok bool
+	var err error
+
+	t.Helper()
+	if args == nil {
+		args = &CLIRunArgs{}
+	}
+	result = newCLIResult(t, args)
+
+	for key, value := range args.Env {
+		t.Setenv(key, value)
+	}
+	if args.Dir != "" {
+		t.Chdir(string(args.Dir))
+	}
+	if args.Stdin != nil {
+		restoreStdin = replaceStdin(t, args.Stdin)
+		defer restoreStdin()
+	}
+
+	prevWriter = cliutil.GetWriter()
+	cliutil.SetWriter(result.Writer)
+	defer restoreCLIWriter(prevWriter)
+
+	remaining, err = cmd.ParseFlagSets(args.Args)
+	if err != nil {
+		result.setError(err, cliutil.ExitOptionsParseError)
+		goto end
+	}
+	err = checkUnknownFlags(remaining)
+	if err != nil {
+		result.setError(err, cliutil.ExitOptionsParseError)
+		goto end
+	}
+	err = cmd.AssignArgs(remaining)
+	if err != nil {
+		result.setError(err, cliutil.ExitOptionsParseError)
+		goto end
+	}
+
+	handler, ok = cmd.(cliutil.CommandHandler)
+	if !ok {
+		err = fmt.Errorf("command '%s' does not implement handler logic", cmd.Name())
+		result.setError(err, cliutil.ExitUnknownRuntimeError)
+		goto end
+	}
+	ctx = args.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	handler.SetCommandRunnerArgs(cliutil.CmdRunnerArgs{
+		Logger:  slog.New(result.LogHandler),
+		Writer:  result.Writer,
+		Context: ctx,
+		Config:  args.Config,
+		Options: args.Options,
+		Args:    args.Args,
+	})
+	result.handle(handler)
+
+end:
+	return result
+}
+
+// restoreCLIWriter reinstalls the cliutil package writer that was set before
+// the command ran, even if none was. cliutil.SetWriter assigns the writer and
+// then panics if it is nil, so the panic is recovered to restore a nil writer.
+func restoreCLIWriter(w cliutil.Writer) {
+	if w == nil {
+		defer func() { _ = recover() }()
+	}
+	cliutil.SetWriter(w)
+}
+
+// checkUnknownFlags returns an error for any flags left over after the
+// command's FlagSets have parsed theirs, as cliutil.CmdRunner does. Negative
+// numbers such as -5 or -1.5 are positional arguments, not flags.
+func checkUnknownFlags(args []string) (err error) {
+	var unknown []string
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if _, numErr := strconv.ParseFloat(arg, 64); numErr == nil {
+			continue
+		}
+		unknown = append(unknown, arg)
+	}
+	if len(unknown) > 0 {
+		err = fmt.Errorf("unknown flag(s): %s", strings.Join(unknown, ", "))
+	}
+	return err
+}
+
+// newCLIResult creates a CLIResult using the writer and handler from args,
+// creating any that were not provided
+func newCLIResult(t *testing.T, args *CLIRunArgs) *CLIResult {
+	w := args.Writer
+	if w == nil {
+		w = NewTestBufferedWriter(t)
+	}
+	if args.SetVerbosity {
+		w.SetVerbosity(args.Verbosity)
+	}
+	if args.Quiet {
+		w.SetQuiet(true)
+	}
+	h := args.LogHandler
+	if h == nil {
+		h = NewBufferedLogHandler()
+	}
+	return &CLIResult{
+		t:          t,
+		Args:       args.Args,
+		Writer:     w,
+		LogHandler: h,
+	}
+}
+
+// handle calls the command's Handle method, converting a panic into an error
+// with the unknown runtime error exit code
+func (r *CLIResult) handle(handler cliutil.CommandHandler) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.setError(fmt.Errorf("command '%s' panicked: %v", handler.Name(), p), cliutil.ExitUnknownRuntimeError)
+		}
+	}()
+	err := handler.Handle()
+	if err != nil {
+		r.setError(err, cliutil.ExitKnownRuntimeError)
+	}
+}
+
+// setError records err along with its exit code, using the code from an
+// ExitCoder in err's chain if there is one
+func (r *CLIResult) setError(err error, code int) {
+	var ec ExitCoder
+	r.Err = err
+	r.ExitCode = code
+	if errors.As(err, &ec) {
+		r.ExitCode = ec.ExitCode()
+	}
+}
+
+// replaceStdin replaces os.Stdin with a pipe fed from r and returns a func
+// that restores the original
+func replaceStdin(t *testing.T, r io.Reader) (restore func()) {
+	var pr, pw *os.File
+	var err error
+
+	orig := os.Stdin
+	f, ok := r.(*os.File)
+	if ok {
+		os.Stdin = f
+		goto end
+	}
+	pr, pw, err = os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create stdin pipe: %v", err)
+	}
+	go func() {
+		_, _ = io.Copy(pw, r)
+		_ = pw.Close()
+	}()
+	os.Stdin = pr
+end:
+	return func() {
+		os.Stdin = orig
+		if pr != nil {
+			_ = pr.Close()
+		}
+		stopInput(r)
+	}
+}
+
+// inputStopper is implemented by readers such as ScriptedInput that need to
+// know when the program reading them has finished
+type inputStopper interface {
+	stopInput()
+}
+
+// stopInput tells r the program reading it has finished, if r cares
+func stopInput(r io.Reader) {
+	if s, ok := r.(inputStopper); ok {
+		s.stopInput()
+	}
+}
@@ -0,0 +1,144 @@
+package testutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// CLIResult holds everything observable about a CLI run and offers fluent
+// assertions on it, e.g.:
+//
+//	testutil.RunCLI(t, cmd, "--name", "foo").
+//		ExpectSuccess().
+//		ExpectStdoutContains("Hello foo")
+type CLIResult struct {
+	t          *testing.T
+	Args       []string
+	Writer     *BufferedWriter
+	LogHandler *BufferedLogHandler
+	ExitCode   int
+	Err        error
+}
+
+// Stdout returns the captured stdout
+func (r *CLIResult) Stdout() string {
+	return r.Writer.GetStdout()
+}
+
+// Stderr returns the captured stderr
+func (r *CLIResult) Stderr() string {
+	return r.Writer.GetStderr()
+}
+
+// Logs returns the captured log entries
+func (r *CLIResult) Logs() LogEntries {
+	r.LogHandler.mu.Lock()
+	defer r.LogHandler.mu.Unlock()
+	return r.LogHandler.entries()
+}
+
+// Timeline returns stdout, stderr and log entries interleaved in the order
+// they were captured
+func (r *CLIResult) Timeline() Timeline {
+	return MergeTimelines(r.Writer.Timeline(), r.LogHandler.Timeline())
+}
+
+// ExpectSuccess fails the test unless the run exited with code 0 and no error
+func (r *CLIResult) ExpectSuccess() *CLIResult {
+	r.t.Helper()
+	if r.ExitCode != 0 || r.Err != nil {
+		r.t.Errorf("Expected success, got exit code %d and error %v%s", r.ExitCode, r.Err, r.transcript())
+	}
+	return r
+}
+
+// ExpectExitCode fails the test unless the run exited with the given code
+func (r *CLIResult) ExpectExitCode(code int) *CLIResult {
+	r.t.Helper()
+	if r.ExitCode != code {
+		r.t.Errorf("Expected exit code %d, got %d (error: %v)%s", code, r.ExitCode, r.Err, r.transcript())
+	}
+	return r
+}
+
+// ExpectError fails the test unless the run returned an error
+func (r *CLIResult) ExpectError() *CLIResult {
+	r.t.Helper()
+	if r.Err == nil {
+		r.t.Errorf("Expected an error, got none%s", r.transcript())
+	}
+	return r
+}
+
+// ExpectErrorIs fails the test unless errors.Is(r.Err, target)
+func (r *CLIResult) ExpectErrorIs(target error) *CLIResult {
+	r.t.Helper()
+	if !errors.Is(r.Err, target) {
+		r.t.Errorf("Expected error matching %v, got %v", target, r.Err)
+	}
+	return r
+}
+
+// ExpectStdout fails the test unless stdout equals want
+func (r *CLIResult) ExpectStdout(want string) *CLIResult {
+	r.t.Helper()
+	if got := r.Stdout(); got != want {
+		r.t.Errorf("Expected stdout %q, got %q", want, got)
+	}
+	return r
+}
+
+// ExpectStdoutContains fails the test unless stdout contains s
+func (r *CLIResult) ExpectStdoutContains(s string) *CLIResult {
+	r.t.Helper()
+	if !r.Writer.ContainsStdout(s) {
+		r.t.Errorf("Expected stdout to contain %q, got %q", s, r.Stdout())
+	}
+	return r
+}
+
+// ExpectStdoutNotContains fails the test if stdout contains s
+func (r *CLIResult) ExpectStdoutNotContains(s string) *CLIResult {
+	r.t.Helper()
+	if r.Writer.ContainsStdout(s) {
+		r.t.Errorf("Expected stdout not to contain %q, got %q", s, r.Stdout())
+	}
+	return r
+}
+
+// ExpectStderrContains fails the test unless stderr contains s
+func (r *CLIResult) ExpectStderrContains(s string) *CLIResult {
+	r.t.Helper()
+	if !r.Writer.ContainsStderr(s) {
+		r.t.Errorf("Expected stderr to contain %q, got %q", s, r.Stderr())
+	}
+	return r
+}
+
+// ExpectStderrEmpty fails the test unless nothing was written to stderr
+func (r *CLIResult) ExpectStderrEmpty() *CLIResult {
+	r.t.Helper()
+	if got := r.Stderr(); got != "" {
+		r.t.Errorf("Expected empty stderr, got %q", got)
+	}
+	return r
+}
+
+// ExpectLogContains fails the test unless a captured log entry contains s
+func (r *CLIResult) ExpectLogContains(s string) *CLIResult {
+	r.t.Helper()
+	if !r.LogHandler.Contains(s) {
+		r.t.Errorf("Expected logs to contain %q, got %q", s, r.LogHandler.String())
+	}
+	return r
+}
+
+// transcript formats the captured output for failure messages
+func (r *CLIResult) transcript() string {
+	tl := r.Timeline()
+	if len(tl) == 0 {
+		return ""
+	}
+	return "\nOutput:\n" + strings.TrimRight(tl.String(), "\n")
+}
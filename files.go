@@ -1,16 +1,21 @@
 package testutil
 
 import (
+	"errors"
+	"io/fs"
 	"testing"
 
 	"github.com/mikeschinkel/go-dt"
 )
 
+// LoadFile reads file, failing the test on error. When mustLoad is false a
+// missing file returns nil instead, but other read errors still fail the test.
 func LoadFile(t *testing.T, file dt.Filepath, mustLoad bool) (data []byte) {
 	var err error
 
+	t.Helper()
 	data, err = file.ReadFile()
-	if err != nil && mustLoad {
+	if err != nil && (mustLoad || !errors.Is(err, fs.ErrNotExist)) {
 		t.Fatal(err)
 	}
 
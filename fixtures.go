@@ -0,0 +1,147 @@
+package testutil
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// FixtureArgs configures the fixture loaders. All fields are optional.
+type FixtureArgs struct {
+	// Optional returns the zero value for a missing file instead of failing
+	// the test. Other read errors always fail the test.
+	Optional bool
+}
+
+// testutilPkgPrefix prefixes the names of functions in this package, so
+// FixturePath can skip them when looking for its caller
+var testutilPkgPrefix = reflect.TypeFor[FixtureArgs]().PkgPath() + "."
+
+// FixturePath returns file relative to the testdata directory of the package
+// of the calling test, found from the caller's source file so it does not
+// depend on the working directory. Absolute paths are returned unchanged.
+// When source paths are unavailable, e.g. with -trimpath, it falls back to
+// testdata in the working directory.
+func FixturePath(file dt.Filepath) dt.Filepath {
+	if filepath.IsAbs(string(file)) {
+		return file
+	}
+	return dt.FilepathJoin(callerTestdata(), file)
+}
+
+// callerTestdata returns the testdata directory beside the source file of the
+// first caller outside this package
+func callerTestdata() (dir dt.DirPath) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	dir = "testdata"
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, testutilPkgPrefix) {
+			if filepath.IsAbs(frame.File) {
+				dir = dt.DirPath(filepath.Join(filepath.Dir(frame.File), "testdata"))
+			}
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	return dir
+}
+
+// LoadFixture reads a fixture file located with FixturePath, failing the test
+// if it cannot be read
+func LoadFixture(t *testing.T, file dt.Filepath, args *FixtureArgs) []byte {
+	t.Helper()
+	data, _ := loadFixture(t, file, args)
+	return data
+}
+
+// loadFixture reads a fixture, returning ok=false for a missing optional file
+func loadFixture(t *testing.T, file dt.Filepath, args *FixtureArgs) (data []byte, ok bool) {
+	var err error
+
+	t.Helper()
+	if args == nil {
+		args = &FixtureArgs{}
+	}
+	file = FixturePath(file)
+	data, err = file.ReadFile()
+	switch {
+	case err == nil:
+		ok = true
+	case errors.Is(err, fs.ErrNotExist) && args.Optional:
+	case errors.Is(err, fs.ErrNotExist):
+		t.Fatalf("Fixture %s does not exist", file)
+	default:
+		t.Fatalf("Failed to read fixture %s: %v", file, err)
+	}
+	return data, ok
+}
+
+// LoadJSON decodes a JSON fixture into a T, failing the test if it cannot be
+// read or decoded, e.g.:
+//
+//	cfg := testutil.LoadJSON[Config](t, "config.json", nil)
+func LoadJSON[T any](t *testing.T, file dt.Filepath, args *FixtureArgs) (v T) {
+	t.Helper()
+	data, ok := loadFixture(t, file, args)
+	if !ok {
+		return v
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		t.Fatalf("Failed to decode JSON fixture %s into %T: %v", file, v, err)
+	}
+	return v
+}
+
+// LoadLines returns the lines of a fixture without their line endings. A
+// final newline does not add an empty line.
+func LoadLines(t *testing.T, file dt.Filepath, args *FixtureArgs) (lines []string) {
+	t.Helper()
+	data, ok := loadFixture(t, file, args)
+	if !ok || len(data) == 0 {
+		return nil
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// LoadTxtar parses a txtar fixture. A missing optional file returns an empty
+// archive.
+func LoadTxtar(t *testing.T, file dt.Filepath, args *FixtureArgs) *TxtarArchive {
+	t.Helper()
+	data, _ := loadFixture(t, file, args)
+	return ParseTxtar(data)
+}
+
+// LoadTemplate renders a text/template fixture with data, e.g. a map of the
+// temp paths a test created. Referencing a missing map key fails the test.
+func LoadTemplate(t *testing.T, file dt.Filepath, data any, args *FixtureArgs) string {
+	var tmpl *template.Template
+	var sb strings.Builder
+
+	t.Helper()
+	text, ok := loadFixture(t, file, args)
+	if !ok {
+		return ""
+	}
+	tmpl, err := template.New(filepath.Base(string(file))).Option("missingkey=error").Parse(string(text))
+	if err == nil {
+		err = tmpl.Execute(&sb, data)
+	}
+	if err != nil {
+		t.Fatalf("Failed to render template fixture %s: %v", file, err)
+	}
+	return sb.String()
+}
@@ -0,0 +1,152 @@
+package testutil
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// FormatCall is a single call to Printf or Errorf recorded with its format
+// string and original arguments. Assertions on them survive changes to the
+// values printed, and error values printed for the user keep their chains.
+// Printf calls suppressed by quiet mode or verbosity are not recorded.
+type FormatCall struct {
+	Seq    uint64
+	Stream string
+	Format string
+	Args   []any
+	// Text is what was written, with error arguments flattened as
+	// cliutil's writer does
+	Text string
+}
+
+// Errors returns the arguments that are errors
+func (c FormatCall) Errors() (errs []error) {
+	for _, arg := range c.Args {
+		err, ok := arg.(error)
+		if ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Arg returns argument i, or nil if there are not that many
+func (c FormatCall) Arg(i int) any {
+	if i < 0 || i >= len(c.Args) {
+		return nil
+	}
+	return c.Args[i]
+}
+
+// IntArg returns argument i if it is any kind of integer
+func (c FormatCall) IntArg(i int) (n int64, ok bool) {
+	v := reflect.ValueOf(c.Arg(i))
+	switch {
+	case v.CanInt():
+		n, ok = v.Int(), true
+	case v.CanUint():
+		n, ok = int64(v.Uint()), true
+	}
+	return n, ok
+}
+
+// FormatCalls returns every Printf and Errorf call in the order they were made
+func (w *BufferedWriter) FormatCalls() []FormatCall {
+	w.out.mu.RLock()
+	defer w.out.mu.RUnlock()
+	return slices.Clone(w.out.calls)
+}
+
+// PrintfCalls returns every Printf call in the order they were made
+func (w *BufferedWriter) PrintfCalls() []FormatCall {
+	return w.callsOn(StdoutStream)
+}
+
+// ErrorfCalls returns every Errorf call in the order they were made
+func (w *BufferedWriter) ErrorfCalls() []FormatCall {
+	return w.callsOn(StderrStream)
+}
+
+func (w *BufferedWriter) callsOn(stream string) []FormatCall {
+	return slices.DeleteFunc(w.FormatCalls(), func(c FormatCall) bool {
+		return c.Stream != stream
+	})
+}
+
+// CallsWithFormat returns the Printf and Errorf calls made with format
+func (w *BufferedWriter) CallsWithFormat(format string) []FormatCall {
+	return slices.DeleteFunc(w.FormatCalls(), func(c FormatCall) bool {
+		return c.Format != format
+	})
+}
+
+// PrintedFormat returns true if Printf or Errorf was called with format
+func (w *BufferedWriter) PrintedFormat(format string) bool {
+	return len(w.CallsWithFormat(format)) > 0
+}
+
+// ExpectFormat fails the test unless Printf or Errorf was called with format
+// and returns the first such call so its arguments can be checked, e.g.:
+//
+//	call := w.ExpectFormat(t, "Processed %d files\n")
+//	if n, _ := call.IntArg(0); n < 10 {
+//		t.Errorf("Expected at least 10 files, got %d", n)
+//	}
+func (w *BufferedWriter) ExpectFormat(t *testing.T, format string) (call FormatCall) {
+	t.Helper()
+	calls := w.CallsWithFormat(format)
+	if len(calls) == 0 {
+		t.Errorf("Expected a call with format %q, got formats %q", format, w.formats())
+		return call
+	}
+	return calls[0]
+}
+
+func (w *BufferedWriter) formats() (formats []string) {
+	for _, c := range w.FormatCalls() {
+		formats = append(formats, c.Format)
+	}
+	return formats
+}
+
+// ReportedErrors returns every error passed as an argument to Errorf
+func (w *BufferedWriter) ReportedErrors() (errs []error) {
+	for _, call := range w.ErrorfCalls() {
+		errs = append(errs, call.Errors()...)
+	}
+	return errs
+}
+
+// ReportedErrorIs returns true if errors.Is(err, target) for any error passed
+// to Errorf, e.g. to check an ErrNotFound was reported to the user
+func (w *BufferedWriter) ReportedErrorIs(target error) bool {
+	for _, err := range w.ReportedErrors() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportedErrorAs finds the first error passed to Errorf that matches target
+// as errors.As does, setting target to it
+func (w *BufferedWriter) ReportedErrorAs(target any) bool {
+	for _, err := range w.ReportedErrors() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectReportedErrorIs fails the test unless an error matching target was
+// passed to the writer's Errorf
+func (r *CLIResult) ExpectReportedErrorIs(target error) *CLIResult {
+	r.t.Helper()
+	if !r.Writer.ReportedErrorIs(target) {
+		r.t.Errorf("Expected an error matching %v to be reported, got %v%s", target, r.Writer.ReportedErrors(), r.transcript())
+	}
+	return r
+}
@@ -0,0 +1,308 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// SnapshotEntry is a single file, directory or symlink in an FSSnapshot
+type SnapshotEntry struct {
+	Path    string
+	Mode    fs.FileMode
+	Content []byte
+	// Symlink is the link's target
+	Symlink string
+}
+
+// IsDir returns true if the entry is a directory
+func (e SnapshotEntry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// FSSnapshot is the state of a directory tree at a point in time, keyed by
+// slash-separated path relative to its root
+type FSSnapshot struct {
+	Root    dt.DirPath
+	Entries map[string]SnapshotEntry
+}
+
+// SnapshotDir records every file, directory and symlink under root along with
+// its mode and content. Symlinks are recorded, not followed.
+func SnapshotDir(t *testing.T, root dt.DirPath) *FSSnapshot {
+	t.Helper()
+	snap := &FSSnapshot{
+		Root:    root,
+		Entries: make(map[string]SnapshotEntry),
+	}
+	err := filepath.WalkDir(string(root), func(p string, d fs.DirEntry, err error) error {
+		var rel string
+		var info fs.FileInfo
+
+		if err != nil {
+			return err
+		}
+		if p == string(root) {
+			return nil
+		}
+		rel, err = filepath.Rel(string(root), p)
+		if err != nil {
+			return err
+		}
+		info, err = d.Info()
+		if err != nil {
+			return err
+		}
+		entry := SnapshotEntry{
+			Path: filepath.ToSlash(rel),
+			Mode: info.Mode(),
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			entry.Symlink, err = os.Readlink(p)
+		case info.Mode().IsRegular():
+			entry.Content, err = os.ReadFile(p)
+		}
+		snap.Entries[entry.Path] = entry
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to snapshot %s: %v", root, err)
+	}
+	return snap
+}
+
+// Paths returns the snapshot's paths in sorted order
+func (s *FSSnapshot) Paths() []string {
+	paths := make([]string, 0, len(s.Entries))
+	for p := range s.Entries {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// String returns a listing of the tree suitable for a golden file: one line
+// per entry with its permissions and path, and the content of each file
+// indented beneath it
+func (s *FSSnapshot) String() string {
+	var sb strings.Builder
+	for _, p := range s.Paths() {
+		e := s.Entries[p]
+		switch {
+		case e.IsDir():
+			fmt.Fprintf(&sb, "%s %s/\n", e.Mode, p)
+		case e.Symlink != "":
+			// Symlink permissions vary by platform so they are left out
+			fmt.Fprintf(&sb, "%-10s %s -> %s\n", "symlink", p, e.Symlink)
+		default:
+			fmt.Fprintf(&sb, "%s %s\n", e.Mode.Perm(), p)
+			writeIndented(&sb, e.Content)
+		}
+	}
+	return sb.String()
+}
+
+// writeIndented writes content with each line indented, or a placeholder for
+// content that is not text
+func writeIndented(sb *strings.Builder, content []byte) {
+	if !isText(content) {
+		fmt.Fprintf(sb, "    <binary, %d bytes>\n", len(content))
+		return
+	}
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		sb.WriteString("    " + line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n    <no newline at end>\n")
+		}
+	}
+}
+
+func isText(data []byte) bool {
+	return !bytes.ContainsRune(data, 0) && strings.ToValidUTF8(string(data), "�") == string(data)
+}
+
+// ExpectGolden fails the test unless the snapshot's listing matches the golden
+// file; see AssertGolden
+func (s *FSSnapshot) ExpectGolden(t *testing.T, file dt.Filepath) {
+	t.Helper()
+	AssertGolden(t, file, []byte(s.String()))
+}
+
+// FSChangeKind is how an entry differs between two snapshots
+type FSChangeKind string
+
+const (
+	FSAdded    FSChangeKind = "added"
+	FSRemoved  FSChangeKind = "removed"
+	FSModified FSChangeKind = "modified"
+)
+
+// FSChange is an entry that differs between two snapshots. Before is empty
+// for added entries and After for removed ones.
+type FSChange struct {
+	Kind   FSChangeKind
+	Path   string
+	Before SnapshotEntry
+	After  SnapshotEntry
+}
+
+// ContentChanged returns true if a modified entry's content or link target
+// changed
+func (c FSChange) ContentChanged() bool {
+	return c.Kind == FSModified &&
+		(!bytes.Equal(c.Before.Content, c.After.Content) || c.Before.Symlink != c.After.Symlink)
+}
+
+// ModeChanged returns true if a modified entry's mode changed
+func (c FSChange) ModeChanged() bool {
+	return c.Kind == FSModified && c.Before.Mode != c.After.Mode
+}
+
+func (c FSChange) String() string {
+	switch {
+	case c.Kind != FSModified:
+		return fmt.Sprintf("%s %s", c.Kind, c.Path)
+	case c.ModeChanged() && c.ContentChanged():
+		return fmt.Sprintf("modified %s (mode %s -> %s, content)", c.Path, c.Before.Mode, c.After.Mode)
+	case c.ModeChanged():
+		return fmt.Sprintf("modified %s (mode %s -> %s)", c.Path, c.Before.Mode, c.After.Mode)
+	}
+	return fmt.Sprintf("modified %s (content)", c.Path)
+}
+
+// FSChanges is the list of changes between two snapshots, sorted by path
+type FSChanges []FSChange
+
+// Diff returns the changes from s to after
+func (s *FSSnapshot) Diff(after *FSSnapshot) (changes FSChanges) {
+	paths := s.Paths()
+	for _, p := range after.Paths() {
+		if _, ok := s.Entries[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	for _, p := range paths {
+		before, inBefore := s.Entries[p]
+		now, inAfter := after.Entries[p]
+		change := FSChange{Path: p, Before: before, After: now}
+		switch {
+		case !inBefore:
+			change.Kind = FSAdded
+		case !inAfter:
+			change.Kind = FSRemoved
+		case before.Mode != now.Mode || before.Symlink != now.Symlink ||
+			(!before.IsDir() && !bytes.Equal(before.Content, now.Content)):
+			change.Kind = FSModified
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Paths returns the changed paths
+func (cc FSChanges) Paths() (paths []string) {
+	for _, c := range cc {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
+
+// String returns one line per change, with a line diff of the content of
+// modified text files
+func (cc FSChanges) String() string {
+	var sb strings.Builder
+	for _, c := range cc {
+		sb.WriteString(c.String() + "\n")
+		if c.ContentChanged() && c.Before.Symlink == "" && isText(c.Before.Content) && isText(c.After.Content) {
+			for _, line := range strings.SplitAfter(LineDiff(string(c.Before.Content), string(c.After.Content)), "\n") {
+				if line != "" {
+					sb.WriteString("    " + line)
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// ExpectNone fails the test if anything changed
+func (cc FSChanges) ExpectNone(t *testing.T) FSChanges {
+	t.Helper()
+	if len(cc) > 0 {
+		t.Errorf("Expected no file changes, got:\n%s", cc)
+	}
+	return cc
+}
+
+// ExpectOnlyUnder fails the test if anything changed outside the given
+// slash-separated directories, e.g. ExpectOnlyUnder(t, "out")
+func (cc FSChanges) ExpectOnlyUnder(t *testing.T, dirs ...string) FSChanges {
+	var outside FSChanges
+
+	t.Helper()
+	for _, c := range cc {
+		if !underAny(c.Path, dirs) {
+			outside = append(outside, c)
+		}
+	}
+	if len(outside) > 0 {
+		t.Errorf("Expected changes only under %q, got:\n%s", dirs, outside)
+	}
+	return cc
+}
+
+func underAny(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = path.Clean(dir)
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Expect fails the test unless the kind of change to each given path matches,
+// e.g. Expect(t, map[string]FSChangeKind{"out/a.txt": FSAdded}). Changes to
+// other paths are ignored.
+func (cc FSChanges) Expect(t *testing.T, want map[string]FSChangeKind) FSChanges {
+	t.Helper()
+	got := make(map[string]FSChangeKind, len(cc))
+	for _, c := range cc {
+		got[c.Path] = c.Kind
+	}
+	for p, kind := range want {
+		if got[p] != kind {
+			t.Errorf("Expected %s to be %s, got %q; changes:\n%s", p, kind, got[p], cc)
+		}
+	}
+	return cc
+}
+
+// WatchDir snapshots root and returns a func that snapshots it again and
+// returns the changes since, e.g.:
+//
+//	changes := testutil.WatchDir(t, root)
+//	testutil.RunCLI(t, cmd, "build")
+//	changes().ExpectOnlyUnder(t, "out")
+func WatchDir(t *testing.T, root dt.DirPath) func() FSChanges {
+	t.Helper()
+	before := SnapshotDir(t, root)
+	return func() FSChanges {
+		t.Helper()
+		return before.Diff(SnapshotDir(t, root))
+	}
+}
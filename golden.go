@@ -0,0 +1,214 @@
+package testutil
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a true value
+// such as 1, rewrites golden files instead of comparing against them
+const UpdateGoldenEnv = "TESTUTIL_UPDATE"
+
+// UpdatingGolden returns true if golden files should be rewritten: when the
+// TESTUTIL_UPDATE environment variable is true, or when the test package
+// defines its own boolean -update flag and it is set. The flag is looked up
+// rather than defined here so it cannot clash with a package's own -update.
+func UpdatingGolden() bool {
+	if update, err := strconv.ParseBool(os.Getenv(UpdateGoldenEnv)); err == nil && update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	update, _ := strconv.ParseBool(f.Value.String())
+	return update
+}
+
+// AssertGolden fails the test unless got matches the contents of file,
+// showing a line diff when it does not. When UpdatingGolden is true the file
+// is written instead, creating its directory if needed.
+func AssertGolden(t *testing.T, file dt.Filepath, got []byte) {
+	var want []byte
+	var err error
+
+	t.Helper()
+	if UpdatingGolden() {
+		err = file.Dir().MkdirAll(0o755)
+		if err == nil {
+			err = file.WriteFile(got, 0o644)
+		}
+		if err != nil {
+			t.Fatalf("Failed to update golden file %s: %v", file, err)
+		}
+		return
+	}
+	want, err = file.ReadFile()
+	if err != nil {
+		t.Fatalf("Failed to read golden file %s (set TESTUTIL_UPDATE=1 to create it): %v", file, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output does not match golden file %s (set TESTUTIL_UPDATE=1 to accept it):\n%s", file, LineDiff(string(want), string(got)))
+	}
+}
+
+// LineDiff returns a diff of want and got with removed lines prefixed by "-",
+// added lines by "+" and unchanged lines by " "
+func LineDiff(want, got string) string {
+	var sb strings.Builder
+
+	a := strings.SplitAfter(want, "\n")
+	b := strings.SplitAfter(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:], b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	writeLine := func(prefix, line string) {
+		if line == "" {
+			return
+		}
+		sb.WriteString(prefix + line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end\n")
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			writeLine(" ", a[i])
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			writeLine("-", a[i])
+			i++
+		default:
+			writeLine("+", b[j])
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// GoldenArgs configures CLIResult.Golden and ExpectGolden. All fields are
+// optional.
+type GoldenArgs struct {
+	// Interleaved writes a single "output" section holding the timeline of
+	// stdout and stderr, and logs if Logs is set, instead of separate sections
+	Interleaved bool
+	// Logs adds the captured log entries
+	Logs bool
+	// Normalizer is applied to every section
+	Normalizer *Normalizer
+}
+
+// Golden returns the result in the combined golden format: a txtar archive
+// with sections for the args, exit code, any error, stdout and stderr or the
+// interleaved output, and optionally the log entries. Output sections are
+// escaped with goldenSection so they round-trip exactly.
+func (r *CLIResult) Golden(args *GoldenArgs) []byte {
+	var logs strings.Builder
+
+	if args == nil {
+		args = &GoldenArgs{}
+	}
+	normalize := func(s string) []byte {
+		if args.Normalizer != nil {
+			s = args.Normalizer.Normalize(s)
+		}
+		return []byte(s)
+	}
+	a := &TxtarArchive{}
+	add := func(name, data string) {
+		a.Files = append(a.Files, TxtarFile{Name: name, Data: normalize(data)})
+	}
+	addOutput := func(name, data string) {
+		a.Files = append(a.Files, TxtarFile{Name: name, Data: goldenSection(normalize(data))})
+	}
+
+	add("args", quoteArgs(r.Args))
+	add("exitcode", strconv.Itoa(r.ExitCode))
+	if r.Err != nil {
+		// Escaped like output as a message may span lines; the newline keeps a
+		// one-line message free of the no-newline marker
+		addOutput("error", r.Err.Error()+"\n")
+	}
+	if args.Interleaved {
+		tl := r.Writer.Timeline()
+		if args.Logs {
+			tl = MergeTimelines(tl, r.LogHandler.Timeline())
+		}
+		addOutput("output", tl.String())
+		return a.Format()
+	}
+	addOutput("stdout", r.Stdout())
+	addOutput("stderr", r.Stderr())
+	if args.Logs {
+		for _, e := range r.LogHandler.Timeline() {
+			logs.WriteString(e.Text)
+		}
+		addOutput("logs", logs.String())
+	}
+	return a.Format()
+}
+
+// ExpectGolden fails the test unless the result matches the combined golden
+// file; see Golden and AssertGolden
+func (r *CLIResult) ExpectGolden(file dt.Filepath, args *GoldenArgs) *CLIResult {
+	r.t.Helper()
+	AssertGolden(r.t, file, r.Golden(args))
+	return r
+}
+
+// goldenNoNewline marks a golden section whose output did not end in a
+// newline, as txtar sections always do
+const goldenNoNewline = "\\ No newline at end\n"
+
+// goldenSection escapes output for a txtar section so that distinct output
+// never compares equal: lines starting with "-- " or a backslash get a
+// backslash prefix so they cannot be read as section markers, and output
+// without a final newline gets a trailing "\ No newline at end" line
+func goldenSection(data []byte) []byte {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("-- ")) || bytes.HasPrefix(line, []byte("\\")) {
+			buf.WriteByte('\\')
+		}
+		buf.Write(line)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		buf.WriteString("\n" + goldenNoNewline)
+	}
+	return buf.Bytes()
+}
+
+// quoteArgs joins args into a single line, quoting any that need it
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\") {
+			quoted[i] = fmt.Sprintf("%q", arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
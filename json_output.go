@@ -0,0 +1,460 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrNotJSON is returned when output expected to be JSON is not
+var ErrNotJSON = errors.New("output is not valid JSON")
+
+// JSONValue is a parsed JSON document. Numbers are kept as json.Number so
+// large integers survive intact.
+type JSONValue struct {
+	value any
+}
+
+// ParseJSON parses data as a single JSON document. Text before or after the
+// document, such as a warning printed to stdout, is an error that quotes the
+// offending text.
+func ParseJSON(data []byte) (v JSONValue, err error) {
+	var rest int64
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&v.value)
+	if err != nil {
+		err = notJSONError(data, dec.InputOffset(), err)
+		goto end
+	}
+	// dec.More reports false for a stray ']' or '}', so anything but io.EOF
+	// from the next token is trailing text
+	rest = dec.InputOffset()
+	_, err = dec.Token()
+	if err == io.EOF {
+		err = nil
+		goto end
+	}
+	rest += int64(len(data[rest:]) - len(bytes.TrimLeft(data[rest:], " \t\r\n")))
+	err = notJSONError(data, rest+1, errors.New("unexpected text after JSON value"))
+end:
+	return v, err
+}
+
+// ParseJSONLines parses data as JSON Lines, one document per non-blank line
+func ParseJSONLines(data []byte) (values []JSONValue, err error) {
+	var v JSONValue
+
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		v, err = ParseJSON([]byte(line))
+		if err != nil {
+			err = fmt.Errorf("line %d: %w", i+1, err)
+			goto end
+		}
+		values = append(values, v)
+	}
+end:
+	return values, err
+}
+
+// notJSONError wraps err with ErrNotJSON and the line of data near offset
+func notJSONError(data []byte, offset int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	offset = min(max(offset-1, 0), int64(len(data)))
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := bytes.IndexByte(data[offset:], '\n')
+	if end < 0 {
+		end = len(data)
+	} else {
+		end += int(offset)
+	}
+	line := bytes.Count(data[:start], []byte("\n")) + 1
+	return fmt.Errorf("%w: %v at line %d: %q", ErrNotJSON, err, line, data[start:end])
+}
+
+// normalizeJSON converts a Go value to the form ParseJSON produces so it can
+// be compared with parsed output
+func normalizeJSON(v any) (norm any, err error) {
+	var data []byte
+
+	jv, ok := v.(JSONValue)
+	if ok {
+		norm = jv.value
+		goto end
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		goto end
+	}
+	norm, err = decodeJSON(data)
+end:
+	return norm, err
+}
+
+func decodeJSON(data []byte) (v any, err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&v)
+	return v, err
+}
+
+// Value returns the parsed document as maps, slices, strings, bools, nil and
+// json.Number
+func (v JSONValue) Value() any {
+	return v.value
+}
+
+// String returns the document as indented JSON
+func (v JSONValue) String() string {
+	data, err := json.MarshalIndent(v.value, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v.value)
+	}
+	return string(data)
+}
+
+// jsonPathSegment matches one segment of a path such as "items[2].name"
+var jsonPathSegment = regexp.MustCompile(`^(?:\.?([^.\[\]]+)|\[(\d+|\*)\])`)
+
+// Get returns the value at path, which is a sequence of object keys and array
+// indexes such as "items[2].name" or "[0].id". An empty path returns the
+// whole document.
+func (v JSONValue) Get(path string) (value any, err error) {
+	var segments []string
+
+	segments, err = parseJSONPath(path)
+	if err != nil {
+		goto end
+	}
+	value = v.value
+	for i, seg := range segments {
+		at := strings.Join(segments[:i+1], "")
+		if strings.HasPrefix(seg, "[") {
+			arr, ok := value.([]any)
+			if !ok {
+				err = fmt.Errorf("%s: not an array", at)
+				goto end
+			}
+			index, convErr := strconv.Atoi(seg[1 : len(seg)-1])
+			if convErr != nil {
+				err = fmt.Errorf("%s: wildcards are only allowed in ignore patterns", at)
+				goto end
+			}
+			if index >= len(arr) {
+				err = fmt.Errorf("%s: index out of range; array has %d elements", at, len(arr))
+				goto end
+			}
+			value = arr[index]
+			continue
+		}
+		obj, ok := value.(map[string]any)
+		if !ok {
+			err = fmt.Errorf("%s: not an object", at)
+			goto end
+		}
+		value, ok = obj[seg[1:]]
+		if !ok {
+			err = fmt.Errorf("%s: no such key", at)
+			goto end
+		}
+	}
+end:
+	return value, err
+}
+
+// parseJSONPath splits path into segments of the form ".key" and "[n]"
+func parseJSONPath(path string) (segments []string, err error) {
+	rest := path
+	for rest != "" {
+		m := jsonPathSegment.FindStringSubmatch(rest)
+		if m == nil {
+			err = fmt.Errorf("invalid JSON path %q at %q", path, rest)
+			break
+		}
+		if m[1] != "" {
+			segments = append(segments, "."+m[1])
+		} else {
+			segments = append(segments, "["+m[2]+"]")
+		}
+		rest = rest[len(m[0]):]
+	}
+	return segments, err
+}
+
+// JSONDiff is a difference found by DiffJSON. Missing is set when the path
+// exists in only one of the values.
+type JSONDiff struct {
+	Path    string
+	Got     any
+	Want    any
+	Missing string
+}
+
+func (d JSONDiff) String() string {
+	path := d.Path
+	if path == "" {
+		path = "(root)"
+	}
+	switch d.Missing {
+	case "got":
+		return fmt.Sprintf("%s: missing, want %s", path, jsonText(d.Want))
+	case "want":
+		return fmt.Sprintf("%s: unexpected %s", path, jsonText(d.Got))
+	}
+	return fmt.Sprintf("%s: got %s, want %s", path, jsonText(d.Got), jsonText(d.Want))
+}
+
+func jsonText(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// DiffJSON compares got and want structurally, ignoring object key order,
+// and returns their differences sorted by path. Either may be a JSONValue, a
+// string or []byte of JSON text, or any value encoding/json can marshal.
+// Paths matching an ignore pattern are skipped; patterns use the Get syntax
+// with "[*]" matching any index and "*" matching any key, e.g.
+// "items[*].id" or "meta.*".
+func DiffJSON(got, want any, ignore ...string) (diffs []JSONDiff, err error) {
+	var g, w any
+	var patterns [][]string
+
+	g, err = toJSON(got)
+	if err != nil {
+		goto end
+	}
+	w, err = toJSON(want)
+	if err != nil {
+		goto end
+	}
+	for _, p := range ignore {
+		var segments []string
+		segments, err = parseJSONPath(p)
+		if err != nil {
+			goto end
+		}
+		patterns = append(patterns, segments)
+	}
+	diffs = diffJSON(nil, g, w, patterns, diffs)
+end:
+	return diffs, err
+}
+
+// toJSON normalizes v, parsing it first if it is JSON text
+func toJSON(v any) (any, error) {
+	switch tv := v.(type) {
+	case string:
+		return decodeJSONText([]byte(tv))
+	case []byte:
+		return decodeJSONText(tv)
+	}
+	return normalizeJSON(v)
+}
+
+func decodeJSONText(data []byte) (any, error) {
+	v, err := ParseJSON(data)
+	return v.value, err
+}
+
+func diffJSON(path []string, got, want any, ignore [][]string, diffs []JSONDiff) []JSONDiff {
+	if jsonPathIgnored(path, ignore) {
+		return diffs
+	}
+	at := strings.TrimPrefix(strings.Join(path, ""), ".")
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			sub := append(slices.Clone(path), "."+k)
+			gv, gok := g[k]
+			wv, wok := w[k]
+			switch {
+			case jsonPathIgnored(sub, ignore):
+			case !gok:
+				diffs = append(diffs, JSONDiff{Path: strings.TrimPrefix(strings.Join(sub, ""), "."), Want: wv, Missing: "got"})
+			case !wok:
+				diffs = append(diffs, JSONDiff{Path: strings.TrimPrefix(strings.Join(sub, ""), "."), Got: gv, Missing: "want"})
+			default:
+				diffs = diffJSON(sub, gv, wv, ignore, diffs)
+			}
+		}
+		return diffs
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(g), len(w)) {
+			sub := append(slices.Clone(path), fmt.Sprintf("[%d]", i))
+			subAt := strings.TrimPrefix(strings.Join(sub, ""), ".")
+			switch {
+			case jsonPathIgnored(sub, ignore):
+			case i >= len(g):
+				diffs = append(diffs, JSONDiff{Path: subAt, Want: w[i], Missing: "got"})
+			case i >= len(w):
+				diffs = append(diffs, JSONDiff{Path: subAt, Got: g[i], Missing: "want"})
+			default:
+				diffs = diffJSON(sub, g[i], w[i], ignore, diffs)
+			}
+		}
+		return diffs
+	default:
+		if jsonScalarEqual(got, want) {
+			return diffs
+		}
+	}
+	return append(diffs, JSONDiff{Path: at, Got: got, Want: want})
+}
+
+// jsonScalarEqual compares non-container values, treating numbers as equal
+// when they have exactly the same value, e.g. 1 and 1.0. Numbers are compared
+// as big.Rat so integers beyond float64 precision stay distinct.
+func jsonScalarEqual(got, want any) bool {
+	gn, gok := got.(json.Number)
+	wn, wok := want.(json.Number)
+	if gok && wok {
+		if gn == wn {
+			return true
+		}
+		gr, gerr := jsonNumberRat(gn)
+		wr, werr := jsonNumberRat(wn)
+		return gerr == nil && werr == nil && gr.Cmp(wr) == 0
+	}
+	switch got.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return got == want
+}
+
+// jsonNumberRat parses n exactly. big.Rat also accepts fractions such as
+// "1/2", which are not JSON numbers, so n is validated first.
+func jsonNumberRat(n json.Number) (r *big.Rat, err error) {
+	var ok bool
+
+	// A valid JSON value starting with a digit or minus sign is a number
+	if n == "" || !strings.ContainsRune("-0123456789", rune(n[0])) || !json.Valid([]byte(n)) {
+		err = fmt.Errorf("invalid JSON number %q", n)
+		goto end
+	}
+	r, ok = new(big.Rat).SetString(string(n))
+	if !ok {
+		err = fmt.Errorf("invalid JSON number %q", n)
+	}
+end:
+	return r, err
+}
+
+// jsonPathIgnored returns true if path matches one of the ignore patterns
+func jsonPathIgnored(path []string, ignore [][]string) bool {
+	for _, pattern := range ignore {
+		if len(pattern) != len(path) {
+			continue
+		}
+		match := true
+		for i, seg := range pattern {
+			switch {
+			case seg == path[i]:
+			case seg == "[*]" && strings.HasPrefix(path[i], "["):
+			case seg == ".*" && strings.HasPrefix(path[i], "."):
+			default:
+				match = false
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON parses stdout as a single JSON document, failing the test if it is not
+func (r *CLIResult) JSON() JSONValue {
+	r.t.Helper()
+	v, err := ParseJSON([]byte(r.Stdout()))
+	if err != nil {
+		r.t.Fatalf("Failed to parse stdout as JSON: %v%s", err, r.transcript())
+	}
+	return v
+}
+
+// JSONLines parses stdout as JSON Lines, failing the test if it is not
+func (r *CLIResult) JSONLines() []JSONValue {
+	r.t.Helper()
+	values, err := ParseJSONLines([]byte(r.Stdout()))
+	if err != nil {
+		r.t.Fatalf("Failed to parse stdout as JSON Lines: %v%s", err, r.transcript())
+	}
+	return values
+}
+
+// ExpectJSON fails the test unless stdout is JSON structurally equal to want,
+// ignoring key order and any paths matching the ignore patterns; see DiffJSON
+func (r *CLIResult) ExpectJSON(want any, ignore ...string) *CLIResult {
+	r.t.Helper()
+	diffs, err := DiffJSON(r.JSON(), want, ignore...)
+	if err != nil {
+		r.t.Fatalf("Failed to compare JSON: %v", err)
+	}
+	if len(diffs) > 0 {
+		r.t.Errorf("Expected stdout JSON to match:\n%s", formatJSONDiffs(diffs))
+	}
+	return r
+}
+
+// ExpectJSONPath fails the test unless the value at path in stdout's JSON
+// equals want, e.g. ExpectJSONPath("items[2].name", "x")
+func (r *CLIResult) ExpectJSONPath(path string, want any) *CLIResult {
+	r.t.Helper()
+	got, err := r.JSON().Get(path)
+	if err != nil {
+		r.t.Errorf("Expected stdout JSON to have %s: %v", path, err)
+		return r
+	}
+	norm, err := normalizeJSON(want)
+	if err != nil {
+		r.t.Fatalf("Failed to compare JSON: %v", err)
+	}
+	if diffs := diffJSON(nil, got, norm, nil, nil); len(diffs) > 0 {
+		r.t.Errorf("Expected %s to be %s, got %s", path, jsonText(want), jsonText(got))
+	}
+	return r
+}
+
+func formatJSONDiffs(diffs []JSONDiff) string {
+	var sb strings.Builder
+	for _, d := range diffs {
+		sb.WriteString("  " + d.String() + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,154 @@
+package testutil
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// LateLogCall describes a log record handled after the test that owned the
+// handler had already finished, typically by a background goroutine
+type LateLogCall struct {
+	Test    string
+	Level   string
+	Message string
+	Source  string // file:line of the log call, if available
+	Time    time.Time
+}
+
+func (c LateLogCall) String() string {
+	src := ""
+	if c.Source != "" {
+		src = " at " + c.Source
+	}
+	return fmt.Sprintf("%s %q logged%s after %s finished", c.Level, c.Message, src, c.Test)
+}
+
+// testGuard records when the test owning a handler has finished
+type testGuard struct {
+	test string
+	done atomic.Bool
+}
+
+func (g *testGuard) ended() bool {
+	return g.done.Load()
+}
+
+// Late log calls from all guarded handlers, reported by AssertNoLateLogs and
+// RunWithLateLogCheck
+var (
+	lateLogCalls    []LateLogCall
+	lateLogReported int
+	lateLogMu       sync.Mutex
+)
+
+func recordLateLogCall(test string, r slog.Record) LateLogCall {
+	call := LateLogCall{
+		Test:    test,
+		Level:   r.Level.String(),
+		Message: r.Message,
+		Source:  recordSource(r),
+		Time:    r.Time,
+	}
+	lateLogMu.Lock()
+	defer lateLogMu.Unlock()
+	lateLogCalls = append(lateLogCalls, call)
+	return call
+}
+
+// GuardTest marks t as the owner of the handler. Records handled after t has
+// finished are still captured but are also recorded as late log calls, which
+// can be reported with LateLogCalls, AssertNoLateLogs or RunWithLateLogCheck.
+// Calling GuardTest again transfers ownership to another test.
+//
+// Only the current owner is tracked. A record carries nothing that ties it to
+// the test whose goroutine logged it, so once ownership moves to the next
+// test, late logs from the previous one are captured as that test's logs and
+// are not detected. Give each test its own handler to catch them.
+func (h *BufferedLogHandler) GuardTest(t *testing.T) *BufferedLogHandler {
+	guard := &testGuard{test: t.Name()}
+	t.Cleanup(func() {
+		guard.done.Store(true)
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.guard = guard
+	return h
+}
+
+// LateLogCalls returns the records this handler received after its guarded
+// test finished
+func (h *BufferedLogHandler) LateLogCalls() []LateLogCall {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.late)
+}
+
+// AssertNoLateLogs fails the test for every late log call from any guarded
+// handler that has not already been reported. Call it at the start of a later
+// test, or at its end, to catch goroutines that outlived earlier tests.
+func AssertNoLateLogs(t *testing.T) {
+	t.Helper()
+	for _, call := range TakeLateLogCalls() {
+		t.Errorf("Late log call: %s", call)
+	}
+}
+
+// RunWithLateLogCheck runs the tests and then reports late log calls with
+// ReportLateLogs. Use it from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(testutil.RunWithLateLogCheck(m))
+//	}
+func RunWithLateLogCheck(m *testing.M) int {
+	return ReportLateLogs(m.Run())
+}
+
+// ReportLateLogs prints a summary of any late log calls not already reported
+// and returns code, or a failing exit code if code was 0 and there were any.
+// Call it after m.Run to combine it with other post-run steps such as
+// RemoveBuiltBinaries:
+//
+//	func TestMain(m *testing.M) {
+//		code := testutil.ReportLateLogs(m.Run())
+//		err := testutil.RemoveBuiltBinaries()
+//		if err != nil {
+//			fmt.Fprintf(os.Stderr, "Failed to remove test binaries: %v\n", err)
+//		}
+//		os.Exit(code)
+//	}
+func ReportLateLogs(code int) int {
+	var calls []LateLogCall
+	var sb strings.Builder
+
+	calls = TakeLateLogCalls()
+	if len(calls) == 0 {
+		goto end
+	}
+	fmt.Fprintf(&sb, "%d log call(s) made after their test finished:\n", len(calls))
+	for _, call := range calls {
+		fmt.Fprintf(&sb, "  %s\n", call)
+	}
+	_, _ = os.Stderr.WriteString(sb.String())
+	if code == 0 {
+		code = 1
+	}
+end:
+	return code
+}
+
+// TakeLateLogCalls returns the late log calls from any guarded handler not
+// already reported and marks them reported, for tests that expect them
+func TakeLateLogCalls() (calls []LateLogCall) {
+	lateLogMu.Lock()
+	defer lateLogMu.Unlock()
+	calls = slices.Clone(lateLogCalls[lateLogReported:])
+	lateLogReported = len(lateLogCalls)
+	return calls
+}
@@ -26,6 +26,7 @@ type LogEntry struct {
 	Message      string   `json:"message"`
 	DateTime     string   `json:"datetime,omitempty"`
 	Attrs        []string `json:"attrs,omitempty"`
+	Test         string   `json:"test,omitempty"`
 	OmitDateTime bool     `json:"-"`
 }
 
@@ -0,0 +1,215 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// LogAttrKind names the kind of value a log attribute must have
+type LogAttrKind string
+
+const (
+	AnyAttr      LogAttrKind = "any"
+	StringAttr   LogAttrKind = "string"
+	IntAttr      LogAttrKind = "int"
+	FloatAttr    LogAttrKind = "float"
+	BoolAttr     LogAttrKind = "bool"
+	DurationAttr LogAttrKind = "duration"
+	TimeAttr     LogAttrKind = "time"
+	ErrorAttr    LogAttrKind = "error"
+	GroupAttr    LogAttrKind = "group"
+)
+
+// SnakeCaseKeys matches snake_case attribute keys; use as LogSchema.KeyPattern
+var SnakeCaseKeys = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// LogSchema declares conventions for log attributes that captured log entries
+// can be validated against. Attribute keys inside groups are matched first by
+// their dotted path, e.g. "request.duration_ms", and then by their own key.
+type LogSchema struct {
+	// KeyPattern, if set, must match every attribute key
+	KeyPattern *regexp.Regexp `json:"key_pattern,omitempty"`
+	// Attrs maps attribute keys to the kind of value they must have
+	Attrs map[string]LogAttrKind `json:"attrs,omitempty"`
+	// Required maps log messages to the attribute keys they must include
+	Required map[string][]string `json:"required,omitempty"`
+}
+
+// ParseLogSchema parses a LogSchema from JSON such as:
+//
+//	{
+//	  "key_pattern": "^[a-z][a-z0-9_]*$",
+//	  "attrs": {"err": "error", "duration_ms": "int"},
+//	  "required": {"request completed": ["request_id", "duration_ms"]}
+//	}
+func ParseLogSchema(data []byte) (schema *LogSchema, err error) {
+	schema = &LogSchema{}
+	err = json.Unmarshal(data, schema)
+	if err != nil {
+		goto end
+	}
+	for key, kind := range schema.Attrs {
+		if !kind.valid() {
+			err = fmt.Errorf("invalid kind %q for log attribute %q", kind, key)
+			goto end
+		}
+	}
+end:
+	return schema, err
+}
+
+// LoadLogSchema loads a LogSchema from a JSON file, failing the test on error
+func LoadLogSchema(t *testing.T, file dt.Filepath) *LogSchema {
+	t.Helper()
+	schema, err := ParseLogSchema(LoadFile(t, file, true))
+	if err != nil {
+		t.Fatalf("Failed to parse log schema %s: %v", file, err)
+	}
+	return schema
+}
+
+func (k LogAttrKind) valid() bool {
+	switch k {
+	case AnyAttr, StringAttr, IntAttr, FloatAttr, BoolAttr, DurationAttr, TimeAttr, ErrorAttr, GroupAttr:
+		return true
+	}
+	return false
+}
+
+// matches returns true if the resolved value v is of kind k
+func (k LogAttrKind) matches(v slog.Value) (ok bool) {
+	switch k {
+	case AnyAttr:
+		ok = true
+	case StringAttr:
+		ok = v.Kind() == slog.KindString
+	case IntAttr:
+		ok = v.Kind() == slog.KindInt64 || v.Kind() == slog.KindUint64
+	case FloatAttr:
+		ok = v.Kind() == slog.KindFloat64
+	case BoolAttr:
+		ok = v.Kind() == slog.KindBool
+	case DurationAttr:
+		ok = v.Kind() == slog.KindDuration
+	case TimeAttr:
+		ok = v.Kind() == slog.KindTime
+	case ErrorAttr:
+		_, ok = v.Any().(error)
+	case GroupAttr:
+		ok = v.Kind() == slog.KindGroup
+	}
+	return ok
+}
+
+// SchemaViolation describes a captured log entry that breaks a LogSchema
+type SchemaViolation struct {
+	Entry   int // 1-based index of the captured entry
+	Message string
+	Key     string
+	Problem string
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("log entry %d %q: attr %q: %s", v.Entry, v.Message, v.Key, v.Problem)
+}
+
+// Validate returns every violation of the schema found in the given records
+func (s *LogSchema) Validate(records []slog.Record) (violations []SchemaViolation) {
+	for i, r := range records {
+		seen := make(map[string]bool)
+		r.Attrs(func(attr slog.Attr) bool {
+			violations = s.validateAttr(violations, i+1, r.Message, "", attr, seen)
+			return true
+		})
+		for _, key := range s.Required[r.Message] {
+			if seen[key] {
+				continue
+			}
+			violations = append(violations, SchemaViolation{
+				Entry:   i + 1,
+				Message: r.Message,
+				Key:     key,
+				Problem: "required attribute is missing",
+			})
+		}
+	}
+	return violations
+}
+
+func (s *LogSchema) validateAttr(violations []SchemaViolation, entry int, msg, prefix string, attr slog.Attr, seen map[string]bool) []SchemaViolation {
+	var path string
+	var kind LogAttrKind
+	var ok bool
+
+	value := attr.Value.Resolve()
+	switch {
+	case attr.Key == "":
+		// Groups with empty keys are inlined by slog
+		path = prefix
+	case prefix == "":
+		path = attr.Key
+	default:
+		path = prefix + "." + attr.Key
+	}
+	if attr.Key == "" {
+		goto group
+	}
+	seen[path] = true
+
+	if s.KeyPattern != nil && !s.KeyPattern.MatchString(attr.Key) {
+		violations = append(violations, SchemaViolation{
+			Entry:   entry,
+			Message: msg,
+			Key:     path,
+			Problem: fmt.Sprintf("key does not match %s", s.KeyPattern),
+		})
+	}
+
+	kind, ok = s.Attrs[path]
+	if !ok {
+		kind, ok = s.Attrs[attr.Key]
+	}
+	if ok && !kind.matches(value) {
+		violations = append(violations, SchemaViolation{
+			Entry:   entry,
+			Message: msg,
+			Key:     path,
+			Problem: fmt.Sprintf("expected %s value, got %s", kind, describeValue(value)),
+		})
+	}
+
+group:
+	if value.Kind() == slog.KindGroup {
+		for _, ga := range value.Group() {
+			violations = s.validateAttr(violations, entry, msg, path, ga, seen)
+		}
+	}
+	return violations
+}
+
+// describeValue names the kind of a slog value for violation messages
+func describeValue(v slog.Value) string {
+	if v.Kind() != slog.KindAny {
+		return strings.ToLower(v.Kind().String())
+	}
+	return fmt.Sprintf("%T", v.Any())
+}
+
+// ValidateSchema returns every violation of the schema in the captured entries
+func (h *BufferedLogHandler) ValidateSchema(schema *LogSchema) []SchemaViolation {
+	return schema.Validate(h.Records())
+}
+
+// AssertSchema fails the test once for each captured entry that breaks the schema
+func (h *BufferedLogHandler) AssertSchema(t *testing.T, schema *LogSchema) {
+	t.Helper()
+	for _, v := range h.ValidateSchema(schema) {
+		t.Errorf("Log schema violation: %s", v)
+	}
+}
@@ -0,0 +1,77 @@
+package testutil
+
+import (
+	"strings"
+)
+
+// Mark is a checkpoint in captured output. Every BufferedWriter and
+// BufferedLogHandler shares one capture sequence, so a mark taken from either
+// can slice the output of both, e.g.:
+//
+//	m := w.Mark()
+//	runStep()
+//	out := w.StdoutSince(m)
+//	logs := handler.EntriesSince(m)
+type Mark uint64
+
+// NewMark returns a mark for the current point in the capture sequence
+func NewMark() Mark {
+	return Mark(captureSeq.Load())
+}
+
+// Mark returns a mark for everything captured so far
+func (w *BufferedWriter) Mark() Mark {
+	return NewMark()
+}
+
+// Mark returns a mark for everything captured so far
+func (h *BufferedLogHandler) Mark() Mark {
+	return NewMark()
+}
+
+// Since returns the entries captured after m
+func (tl Timeline) Since(m Mark) (since Timeline) {
+	for _, e := range tl {
+		if e.Seq > uint64(m) {
+			since = append(since, e)
+		}
+	}
+	return since
+}
+
+// TimelineSince returns the writes made after m
+func (w *BufferedWriter) TimelineSince(m Mark) Timeline {
+	return w.Timeline().Since(m)
+}
+
+// StdoutSince returns what was written to stdout after m
+func (w *BufferedWriter) StdoutSince(m Mark) string {
+	return w.streamSince(StdoutStream, m)
+}
+
+// StderrSince returns what was written to stderr after m
+func (w *BufferedWriter) StderrSince(m Mark) string {
+	return w.streamSince(StderrStream, m)
+}
+
+func (w *BufferedWriter) streamSince(stream string, m Mark) string {
+	var sb strings.Builder
+	for _, e := range w.TimelineSince(m) {
+		if e.Stream == stream {
+			sb.WriteString(e.Text)
+		}
+	}
+	return sb.String()
+}
+
+// EntriesSince returns the log entries captured after m
+func (h *BufferedLogHandler) EntriesSince(m Mark) (entries LogEntries) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, cr := range h.records {
+		if cr.seq > uint64(m) {
+			entries = append(entries, cr.entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,728 @@
+package testutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// FSOp is a MemFS operation that a fault can be injected into
+type FSOp string
+
+const (
+	FSOpOpen   FSOp = "open"
+	FSOpStat   FSOp = "stat"
+	FSOpRead   FSOp = "read"
+	FSOpCreate FSOp = "create"
+	FSOpWrite  FSOp = "write"
+	FSOpMkdir  FSOp = "mkdir"
+	FSOpRemove FSOp = "remove"
+	FSOpRename FSOp = "rename"
+	FSOpChmod  FSOp = "chmod"
+)
+
+// FSFault makes matching MemFS operations fail with Err, e.g. failing the
+// third write to any .db file with ENOSPC:
+//
+//	mfs.InjectFault(testutil.FSFault{Op: testutil.FSOpWrite, Pattern: "*.db", Nth: 3, Err: syscall.ENOSPC})
+type FSFault struct {
+	// Op is the operation to fail; empty matches every operation
+	Op FSOp
+	// Pattern is a path.Match pattern matched against the full path and
+	// against its base name; empty matches every path. Renames match the old
+	// path.
+	Pattern string
+	// Nth fails only the Nth matching operation, counting from 1; zero fails
+	// every matching operation
+	Nth int
+	// Err is wrapped in an *fs.PathError, or an *os.LinkError for renames
+	Err error
+}
+
+// memFault is an injected fault and the number of operations it has matched
+type memFault struct {
+	FSFault
+	matched int
+}
+
+// memNode is a file or directory in a MemFS
+type memNode struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// MemFS is an in-memory filesystem implementing fs.FS, fs.ReadFileFS,
+// fs.StatFS and fs.ReadDirFS along with write operations modelled on the os
+// package. Paths are slash-separated and unrooted as with fs.ValidPath.
+// Directories without write permission reject changes to their entries, and
+// faults can be injected into any operation with InjectFault. It is safe for
+// concurrent use.
+type MemFS struct {
+	mu     sync.Mutex
+	nodes  map[string]*memNode
+	faults []*memFault
+}
+
+// NewMemFS returns an empty MemFS
+func NewMemFS() *MemFS {
+	return &MemFS{
+		nodes: map[string]*memNode{
+			".": {mode: fs.ModeDir | 0o755, modTime: time.Now()},
+		},
+	}
+}
+
+// NewTestMemFS returns a MemFS holding spec, failing the test if spec cannot
+// be built in memory, e.g. because it has symlinks
+func NewTestMemFS(t *testing.T, spec TreeSpec) *MemFS {
+	var entries map[string]TreeEntry
+	var names []string
+	var err error
+
+	t.Helper()
+	m := NewMemFS()
+	entries, err = spec.treeEntries()
+	if err != nil {
+		t.Fatalf("Failed to read tree spec: %v", err)
+	}
+	for name := range entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		err = m.addTreeEntry(strings.TrimSuffix(name, "/"), entries[name])
+		if err != nil {
+			t.Fatalf("Failed to create %s in MemFS: %v", name, err)
+		}
+	}
+	return m
+}
+
+// addTreeEntry adds a tree entry, ignoring faults and permissions
+func (m *MemFS) addTreeEntry(name string, e TreeEntry) (err error) {
+	var mode fs.FileMode
+
+	if e.Symlink != "" {
+		err = fmt.Errorf("symlinks are not supported")
+		goto end
+	}
+	if !fs.ValidPath(name) || name == "." {
+		err = fmt.Errorf("invalid tree path %q", name)
+		goto end
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mkdirAllLocked(path.Dir(name), 0o755)
+	mode = e.Mode.Perm()
+	switch {
+	case e.Dir && mode == 0:
+		mode = fs.ModeDir | 0o755
+	case e.Dir:
+		mode |= fs.ModeDir
+	case mode == 0:
+		mode = 0o644
+	}
+	if e.Dir && m.nodes[name] != nil {
+		m.nodes[name].mode = mode
+		goto end
+	}
+	m.nodes[name] = &memNode{data: []byte(e.Content), mode: mode, modTime: e.ModTime}
+	if e.ModTime.IsZero() {
+		m.nodes[name].modTime = time.Now()
+	}
+end:
+	return err
+}
+
+// InjectFault adds a fault. Faults are checked in the order they were added
+// and the first one that fails an operation wins.
+func (m *MemFS) InjectFault(f FSFault) *MemFS {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = append(m.faults, &memFault{FSFault: f})
+	return m
+}
+
+// ClearFaults removes every injected fault
+func (m *MemFS) ClearFaults() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = nil
+}
+
+// fault returns the error of the first fault that fails op on name, counting
+// the operation against every fault that matches it
+func (m *MemFS) fault(op FSOp, name string) (err error) {
+	for _, f := range m.faults {
+		if f.Op != "" && f.Op != op {
+			continue
+		}
+		if f.Pattern != "" && !matchPath(f.Pattern, name) {
+			continue
+		}
+		f.matched++
+		if err == nil && (f.Nth == 0 || f.Nth == f.matched) {
+			err = f.Err
+		}
+	}
+	return err
+}
+
+func matchPath(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	if !ok {
+		ok, _ = path.Match(pattern, path.Base(name))
+	}
+	return ok
+}
+
+// check validates name and applies any fault for op, returning an
+// *fs.PathError on failure
+func (m *MemFS) check(op FSOp, name string) (err error) {
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	err = m.fault(op, name)
+end:
+	if err != nil {
+		err = pathError(op, name, err)
+	}
+	return err
+}
+
+// writableDir returns an error unless dir exists, is a directory and allows
+// changes to its entries
+func (m *MemFS) writableDir(dir string) (err error) {
+	n := m.nodes[dir]
+	switch {
+	case n == nil:
+		err = fs.ErrNotExist
+	case !n.mode.IsDir():
+		err = errors.New("not a directory")
+	case n.mode.Perm()&0o200 == 0:
+		err = fs.ErrPermission
+	}
+	return err
+}
+
+// Open implements fs.FS. Read permission is not checked: files and
+// directories can be opened and read whatever their mode, so use InjectFault
+// with FSOpOpen or FSOpRead to simulate unreadable files.
+func (m *MemFS) Open(name string) (f fs.File, err error) {
+	var n *memNode
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpOpen, name)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[name]
+	if n == nil {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		goto end
+	}
+	f = &MemFile{fs: m, name: name, node: n}
+end:
+	return f, err
+}
+
+// ReadFile implements fs.ReadFileFS
+func (m *MemFS) ReadFile(name string) (data []byte, err error) {
+	var n *memNode
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpRead, name)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[name]
+	switch {
+	case n == nil:
+		err = &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	case n.mode.IsDir():
+		err = &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	default:
+		data = bytes.Clone(n.data)
+	}
+end:
+	return data, err
+}
+
+// Stat implements fs.StatFS
+func (m *MemFS) Stat(name string) (info fs.FileInfo, err error) {
+	var n *memNode
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpStat, name)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[name]
+	if n == nil {
+		err = pathError(FSOpStat, name, fs.ErrNotExist)
+		goto end
+	}
+	info = newMemInfo(name, n)
+end:
+	return info, err
+}
+
+// ReadDir implements fs.ReadDirFS, returning entries sorted by name
+func (m *MemFS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpRead, name)
+	if err != nil {
+		goto end
+	}
+	if n := m.nodes[name]; n == nil || !n.mode.IsDir() {
+		err = &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		goto end
+	}
+	entries = m.readDirLocked(name)
+end:
+	return entries, err
+}
+
+func (m *MemFS) readDirLocked(dir string) (entries []fs.DirEntry) {
+	for _, child := range m.children(dir) {
+		entries = append(entries, fs.FileInfoToDirEntry(newMemInfo(child, m.nodes[child])))
+	}
+	return entries
+}
+
+// children returns the sorted paths of the direct entries of dir
+func (m *MemFS) children(dir string) (names []string) {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	for name := range m.nodes {
+		if name == "." || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !strings.Contains(name[len(prefix):], "/") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Create creates or truncates the named file with mode 0o644 and opens it for
+// writing, like os.Create
+func (m *MemFS) Create(name string) (*MemFile, error) {
+	return m.create(name, 0o644)
+}
+
+// create opens name for writing, creating it with perm if needed
+func (m *MemFS) create(name string, perm fs.FileMode) (f *MemFile, err error) {
+	var n *memNode
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	err = m.fault(FSOpCreate, name)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[name]
+	switch {
+	case n == nil:
+		err = m.writableDir(path.Dir(name))
+		if err != nil {
+			goto end
+		}
+		n = &memNode{mode: perm.Perm()}
+		m.nodes[name] = n
+	case n.mode.IsDir():
+		err = errors.New("is a directory")
+		goto end
+	case n.mode.Perm()&0o200 == 0:
+		err = fs.ErrPermission
+		goto end
+	}
+	n.data = nil
+	n.modTime = time.Now()
+	f = &MemFile{fs: m, name: name, node: n, writable: true}
+end:
+	if err != nil {
+		err = pathError(FSOpCreate, name, err)
+	}
+	return f, err
+}
+
+// WriteFile creates or truncates the named file and writes data to it in a
+// single write, like os.WriteFile. perm is used only if the file is created.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) (err error) {
+	var f *MemFile
+
+	f, err = m.create(name, perm)
+	if err != nil {
+		goto end
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		goto end
+	}
+	err = f.Close()
+end:
+	return err
+}
+
+// Mkdir creates a directory whose parent must exist, like os.Mkdir
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpMkdir, name)
+	if err != nil {
+		goto end
+	}
+	if m.nodes[name] != nil {
+		err = pathError(FSOpMkdir, name, fs.ErrExist)
+		goto end
+	}
+	err = m.writableDir(path.Dir(name))
+	if err != nil {
+		err = pathError(FSOpMkdir, name, err)
+		goto end
+	}
+	m.nodes[name] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+end:
+	return err
+}
+
+// MkdirAll creates a directory and any missing parents, like os.MkdirAll
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) (err error) {
+	var dirs []string
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(name) {
+		// Checked first as path.Dir never reaches "." from a rooted path
+		err = pathError(FSOpMkdir, name, fs.ErrInvalid)
+		goto end
+	}
+	for dir := name; dir != "." && m.nodes[dir] == nil; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	slices.Reverse(dirs)
+	for _, dir := range dirs {
+		err = m.check(FSOpMkdir, dir)
+		if err != nil {
+			goto end
+		}
+		err = m.writableDir(path.Dir(dir))
+		if err != nil {
+			err = pathError(FSOpMkdir, dir, err)
+			goto end
+		}
+		m.nodes[dir] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	if n := m.nodes[name]; n != nil && !n.mode.IsDir() {
+		err = pathError(FSOpMkdir, name, errors.New("not a directory"))
+	}
+end:
+	return err
+}
+
+// mkdirAllLocked creates dir and its parents ignoring faults and permissions
+func (m *MemFS) mkdirAllLocked(dir string, perm fs.FileMode) {
+	for ; dir != "." && m.nodes[dir] == nil; dir = path.Dir(dir) {
+		m.nodes[dir] = &memNode{mode: fs.ModeDir | perm, modTime: time.Now()}
+	}
+}
+
+// Remove removes a file or empty directory, like os.Remove
+func (m *MemFS) Remove(name string) (err error) {
+	var n *memNode
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpRemove, name)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[name]
+	switch {
+	case n == nil || name == ".":
+		err = fs.ErrNotExist
+	case n.mode.IsDir() && len(m.children(name)) > 0:
+		err = errors.New("directory not empty")
+	default:
+		err = m.writableDir(path.Dir(name))
+	}
+	if err != nil {
+		err = pathError(FSOpRemove, name, err)
+		goto end
+	}
+	delete(m.nodes, name)
+end:
+	return err
+}
+
+// RemoveAll removes name and anything it contains, like os.RemoveAll. A
+// missing path is not an error, and removing "." fails with EINVAL.
+func (m *MemFS) RemoveAll(name string) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpRemove, name)
+	if err != nil || m.nodes[name] == nil {
+		goto end
+	}
+	if name == "." {
+		err = pathError(FSOpRemove, name, syscall.EINVAL)
+		goto end
+	}
+	err = m.writableDir(path.Dir(name))
+	if err != nil {
+		err = pathError(FSOpRemove, name, err)
+		goto end
+	}
+	for p := range m.nodes {
+		if p == name || strings.HasPrefix(p, name+"/") {
+			delete(m.nodes, p)
+		}
+	}
+end:
+	return err
+}
+
+// Rename moves oldName to newName, replacing any file or empty directory at
+// newName, like os.Rename. Moving a directory into itself fails with EINVAL,
+// onto a non-empty directory with ENOTEMPTY, onto a file with ENOTDIR, and a
+// file onto a directory with EISDIR. Errors are *os.LinkError.
+func (m *MemFS) Rename(oldName, newName string) (err error) {
+	var n, existing *memNode
+	var moved []string
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(oldName) || !fs.ValidPath(newName) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	err = m.fault(FSOpRename, oldName)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[oldName]
+	if n == nil {
+		err = fs.ErrNotExist
+		goto end
+	}
+	if oldName == "." || newName == "." || newName == oldName || strings.HasPrefix(newName, oldName+"/") {
+		// A directory cannot be moved into itself
+		err = syscall.EINVAL
+		goto end
+	}
+	existing = m.nodes[newName]
+	switch {
+	case existing == nil:
+	case existing.mode.IsDir() && !n.mode.IsDir():
+		err = syscall.EISDIR
+	case !existing.mode.IsDir() && n.mode.IsDir():
+		err = syscall.ENOTDIR
+	case existing.mode.IsDir() && len(m.children(newName)) > 0:
+		err = syscall.ENOTEMPTY
+	}
+	if err != nil {
+		goto end
+	}
+	err = m.writableDir(path.Dir(oldName))
+	if err == nil {
+		err = m.writableDir(path.Dir(newName))
+	}
+	if err != nil {
+		goto end
+	}
+	// Collected first so the map is not changed while ranging over it
+	for p := range m.nodes {
+		if p == oldName || strings.HasPrefix(p, oldName+"/") {
+			moved = append(moved, p)
+		}
+	}
+	delete(m.nodes, newName)
+	for _, p := range moved {
+		node := m.nodes[p]
+		delete(m.nodes, p)
+		m.nodes[newName+p[len(oldName):]] = node
+	}
+end:
+	if err != nil {
+		err = &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+	}
+	return err
+}
+
+// Chmod changes the permissions of name, like os.Chmod. Removing write
+// permission from a directory makes it read-only: entries can no longer be
+// created, removed or renamed in it.
+func (m *MemFS) Chmod(name string, mode fs.FileMode) (err error) {
+	var n *memNode
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.check(FSOpChmod, name)
+	if err != nil {
+		goto end
+	}
+	n = m.nodes[name]
+	if n == nil {
+		err = pathError(FSOpChmod, name, fs.ErrNotExist)
+		goto end
+	}
+	n.mode = n.mode.Type() | mode.Perm()
+end:
+	return err
+}
+
+func pathError(op FSOp, name string, err error) error {
+	return &fs.PathError{Op: string(op), Path: name, Err: err}
+}
+
+// MemFile is an open MemFS file or directory. Files opened with Create are
+// writable; those opened with Open are read-only.
+type MemFile struct {
+	fs       *MemFS
+	name     string
+	node     *memNode
+	writable bool
+	offset   int
+	dirRead  int
+	closed   bool
+}
+
+var _ fs.ReadDirFile = (*MemFile)(nil)
+
+// Stat implements fs.File
+func (f *MemFile) Stat() (fs.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	return newMemInfo(f.name, f.node), nil
+}
+
+// Read implements fs.File
+func (f *MemFile) Read(p []byte) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	switch {
+	case f.closed:
+		err = fs.ErrClosed
+	case f.node.mode.IsDir():
+		err = errors.New("is a directory")
+	default:
+		err = f.fs.fault(FSOpRead, f.name)
+	}
+	if err != nil {
+		err = pathError(FSOpRead, f.name, err)
+		goto end
+	}
+	if f.offset >= len(f.node.data) {
+		err = io.EOF
+		goto end
+	}
+	n = copy(p, f.node.data[f.offset:])
+	f.offset += n
+end:
+	return n, err
+}
+
+// Write appends p to a file opened with Create. Each call is one write for
+// fault injection.
+func (f *MemFile) Write(p []byte) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	switch {
+	case f.closed:
+		err = fs.ErrClosed
+	case !f.writable:
+		err = fs.ErrPermission
+	default:
+		err = f.fs.fault(FSOpWrite, f.name)
+	}
+	if err != nil {
+		err = pathError(FSOpWrite, f.name, err)
+		goto end
+	}
+	f.node.data = append(f.node.data, p...)
+	f.node.modTime = time.Now()
+	n = len(p)
+end:
+	return n, err
+}
+
+// ReadDir implements fs.ReadDirFile
+func (f *MemFile) ReadDir(count int) (entries []fs.DirEntry, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if !f.node.mode.IsDir() {
+		err = pathError("readdir", f.name, errors.New("not a directory"))
+		goto end
+	}
+	entries = f.fs.readDirLocked(f.name)
+	entries = entries[min(f.dirRead, len(entries)):]
+	if count > 0 {
+		if len(entries) == 0 {
+			err = io.EOF
+			goto end
+		}
+		entries = entries[:min(count, len(entries))]
+	}
+	f.dirRead += len(entries)
+end:
+	return entries, err
+}
+
+// Close implements fs.File
+func (f *MemFile) Close() (err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		err = pathError("close", f.name, fs.ErrClosed)
+	}
+	f.closed = true
+	return err
+}
+
+// memInfo implements fs.FileInfo with a copy of a MemFS node's metadata
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func newMemInfo(name string, n *memNode) memInfo {
+	return memInfo{
+		name:    path.Base(name),
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
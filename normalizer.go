@@ -0,0 +1,185 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// NormalizeRule replaces every match of Pattern with Replace, which may use
+// $1-style references, or with the result of ReplaceFunc when it is set
+type NormalizeRule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replace     string
+	ReplaceFunc func(match string) string
+}
+
+func (r NormalizeRule) apply(s string) string {
+	if r.ReplaceFunc != nil {
+		return r.Pattern.ReplaceAllStringFunc(s, r.ReplaceFunc)
+	}
+	return r.Pattern.ReplaceAllString(s, r.Replace)
+}
+
+// Normalizer rewrites the unstable parts of captured output, such as temp
+// paths, durations and timestamps, into stable placeholders so it can be
+// compared against expected output. Rules are applied in the order they were
+// added.
+type Normalizer struct {
+	rules []NormalizeRule
+}
+
+// Patterns used by the built-in normalization rules
+var (
+	windowsPathPattern = regexp.MustCompile(`(?:\b[A-Za-z]:|\\\\[^\\\s"'<>|:*?]+)(?:\\[^\\\s"'<>|:*?]+)+\\?`)
+	uuidPattern        = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	timestampPattern   = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	durationPattern    = regexp.MustCompile(`\b(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+\b`)
+	pidPattern         = regexp.MustCompile(`(?i)\b(pid[=: ]+)\d+\b`)
+)
+
+// NewNormalizer returns a Normalizer with the built-in rules, in order:
+//
+//	windows_paths  C:\a\b and \\server\share become C:/a/b and //server/share
+//	tmpdir         t.TempDir() directories of any test become <TMPDIR>
+//	home           the user's home directory becomes <HOME>
+//	uuid           UUIDs become <UUID>
+//	timestamp      RFC 3339 and "2006-01-02 15:04:05" times become <TIME>
+//	duration       Go durations such as 1.5s or 2m3s become <DURATION>
+//	pid            pid=123 becomes pid=<PID>
+//
+// Only backslash paths starting with a drive letter or UNC prefix are
+// rewritten, so escapes such as a\nb in JSON are left alone.
+func NewNormalizer() *Normalizer {
+	n := &Normalizer{}
+	n.AddRule(NormalizeRule{
+		Name:    "windows_paths",
+		Pattern: windowsPathPattern,
+		ReplaceFunc: func(match string) string {
+			return strings.ReplaceAll(match, `\`, "/")
+		},
+	})
+	// Every t.TempDir() is a numbered directory in a per-test directory
+	// under os.TempDir(), such as /tmp/TestName1234/001
+	n.addPathRule("tmpdir", os.TempDir(), `/[^/\s]+/\d+`, "<TMPDIR>")
+	home, err := os.UserHomeDir()
+	if err == nil && home != "" && home != "/" {
+		n.addPathRule("home", home, "", "<HOME>")
+	}
+	n.AddReplacer("uuid", uuidPattern, "<UUID>")
+	n.AddReplacer("timestamp", timestampPattern, "<TIME>")
+	n.AddReplacer("duration", durationPattern, "<DURATION>")
+	n.AddReplacer("pid", pidPattern, "${1}<PID>")
+	return n
+}
+
+// addPathRule adds a rule replacing dir, in both its given and symlink
+// resolved forms and with forward slashes, followed by suffix. The match must
+// end at a path boundary so a sibling such as /tmpfoo is left alone.
+func (n *Normalizer) addPathRule(name, dir, suffix, placeholder string) {
+	var alts []string
+
+	forms := []string{dir}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err == nil {
+		forms = append(forms, resolved)
+	}
+	for _, form := range forms {
+		form = filepath.ToSlash(form)
+		if !slices.Contains(alts, regexp.QuoteMeta(form)) {
+			alts = append(alts, regexp.QuoteMeta(form))
+		}
+	}
+	// Longest first so a resolved path is not partially replaced
+	slices.SortFunc(alts, func(a, b string) int { return len(b) - len(a) })
+	n.AddReplacer(name, regexp.MustCompile(`(?:`+strings.Join(alts, "|")+`)`+suffix+`(?P<end>/|$|[^\w.-])`), placeholder+"${end}")
+}
+
+// AddRule appends rule, replacing any existing rule with the same name
+func (n *Normalizer) AddRule(rule NormalizeRule) *Normalizer {
+	i := slices.IndexFunc(n.rules, func(r NormalizeRule) bool { return r.Name == rule.Name })
+	if i >= 0 {
+		n.rules[i] = rule
+		return n
+	}
+	n.rules = append(n.rules, rule)
+	return n
+}
+
+// AddReplacer adds a rule replacing matches of pattern with replacement
+func (n *Normalizer) AddReplacer(name string, pattern *regexp.Regexp, replacement string) *Normalizer {
+	return n.AddRule(NormalizeRule{
+		Name:    name,
+		Pattern: pattern,
+		Replace: replacement,
+	})
+}
+
+// AddLiteral adds a rule replacing every occurrence of literal with placeholder
+func (n *Normalizer) AddLiteral(name, literal, placeholder string) *Normalizer {
+	return n.AddReplacer(name, regexp.MustCompile(regexp.QuoteMeta(literal)), strings.ReplaceAll(placeholder, "$", "$$"))
+}
+
+// RemoveRule removes the named rule, e.g. to keep durations in output that
+// is expected to contain them
+func (n *Normalizer) RemoveRule(name string) *Normalizer {
+	n.rules = slices.DeleteFunc(n.rules, func(r NormalizeRule) bool { return r.Name == name })
+	return n
+}
+
+// Rules returns the names of the normalizer's rules in the order they apply
+func (n *Normalizer) Rules() (names []string) {
+	for _, r := range n.rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// Normalize applies every rule to s
+func (n *Normalizer) Normalize(s string) string {
+	for _, r := range n.rules {
+		s = r.apply(s)
+	}
+	return s
+}
+
+// NormalizeTimeline returns a copy of tl with every entry's text normalized
+func (n *Normalizer) NormalizeTimeline(tl Timeline) Timeline {
+	tl = slices.Clone(tl)
+	for i := range tl {
+		tl[i].Text = n.Normalize(tl[i].Text)
+	}
+	return tl
+}
+
+// NormalizedStdout returns stdout normalized by n
+func (w *BufferedWriter) NormalizedStdout(n *Normalizer) string {
+	return n.Normalize(w.GetStdout())
+}
+
+// NormalizedStderr returns stderr normalized by n
+func (w *BufferedWriter) NormalizedStderr(n *Normalizer) string {
+	return n.Normalize(w.GetStderr())
+}
+
+// NormalizedEntries returns the captured log entries with their messages,
+// attributes and times normalized by n
+func (h *BufferedLogHandler) NormalizedEntries(n *Normalizer) LogEntries {
+	h.mu.Lock()
+	entries := h.entries()
+	h.mu.Unlock()
+
+	for i := range entries {
+		e := &entries[i]
+		e.Message = n.Normalize(e.Message)
+		e.DateTime = n.Normalize(e.DateTime)
+		e.Attrs = slices.Clone(e.Attrs)
+		for j, attr := range e.Attrs {
+			e.Attrs[j] = n.Normalize(attr)
+		}
+	}
+	return entries
+}
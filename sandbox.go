@@ -0,0 +1,112 @@
+package testutil
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// SandboxArgs configures Sandbox. All fields are optional.
+type SandboxArgs struct {
+	// Clear lists variables to unset, such as a CLI's own config variables
+	Clear []string
+	// ClearPrefixes unsets every variable starting with one of the prefixes,
+	// e.g. "MYAPP_"
+	ClearPrefixes []string
+	// Env sets variables after the sandbox directories are in place, so it
+	// can override them
+	Env map[string]string
+	// HomeFiles is written under the sandbox's Home
+	HomeFiles TreeSpec
+	// ConfigFiles is written under the sandbox's ConfigHome, e.g.
+	// TreeFiles{"myapp/config.json": `{"debug":true}`}
+	ConfigFiles TreeSpec
+}
+
+// SandboxEnv holds the directories Sandbox points the environment at
+type SandboxEnv struct {
+	Home       dt.DirPath
+	ConfigHome dt.DirPath
+	CacheHome  dt.DirPath
+	DataHome   dt.DirPath
+	StateHome  dt.DirPath
+}
+
+// Sandbox points HOME and the XDG base directories at fresh temp directories,
+// along with USERPROFILE, APPDATA and LOCALAPPDATA on Windows, so that
+// os.UserHomeDir, os.UserConfigDir and os.UserCacheDir do not find a
+// developer's real config. On macOS os.UserConfigDir and os.UserCacheDir are
+// under Home rather than ConfigHome and CacheHome.
+//
+// Every variable is restored when the test ends. Because the process
+// environment is shared, Sandbox panics if the test or an ancestor called
+// t.Parallel, and the test cannot call t.Parallel afterwards; see t.Setenv.
+func Sandbox(t *testing.T, args *SandboxArgs) *SandboxEnv {
+	t.Helper()
+	if args == nil {
+		args = &SandboxArgs{}
+	}
+	root := dt.DirPath(t.TempDir())
+	sb := &SandboxEnv{
+		Home:       dt.DirPathJoin(root, "home"),
+		ConfigHome: dt.DirPathJoin(root, "config"),
+		CacheHome:  dt.DirPathJoin(root, "cache"),
+		DataHome:   dt.DirPathJoin(root, "data"),
+		StateHome:  dt.DirPathJoin(root, "state"),
+	}
+	for _, dir := range []dt.DirPath{sb.Home, sb.ConfigHome, sb.CacheHome, sb.DataHome, sb.StateHome} {
+		err := dir.MkdirAll(0o755)
+		if err != nil {
+			t.Fatalf("Failed to create sandbox directory %s: %v", dir, err)
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, prefix := range args.ClearPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				unsetenv(t, name)
+				break
+			}
+		}
+	}
+	for _, name := range args.Clear {
+		unsetenv(t, name)
+	}
+
+	t.Setenv("HOME", string(sb.Home))
+	t.Setenv("XDG_CONFIG_HOME", string(sb.ConfigHome))
+	t.Setenv("XDG_CACHE_HOME", string(sb.CacheHome))
+	t.Setenv("XDG_DATA_HOME", string(sb.DataHome))
+	t.Setenv("XDG_STATE_HOME", string(sb.StateHome))
+	if runtime.GOOS == "windows" {
+		t.Setenv("USERPROFILE", string(sb.Home))
+		t.Setenv("APPDATA", string(sb.ConfigHome))
+		t.Setenv("LOCALAPPDATA", string(sb.CacheHome))
+	}
+	for name, value := range args.Env {
+		t.Setenv(name, value)
+	}
+
+	if args.HomeFiles != nil {
+		WriteTree(t, sb.Home, args.HomeFiles)
+	}
+	if args.ConfigFiles != nil {
+		WriteTree(t, sb.ConfigHome, args.ConfigFiles)
+	}
+	return sb
+}
+
+// unsetenv unsets name until the test ends. t.Setenv records the original
+// value for restoring and enforces the parallel test check.
+func unsetenv(t *testing.T, name string) {
+	t.Helper()
+	t.Setenv(name, "")
+	err := os.Unsetenv(name)
+	if err != nil {
+		t.Fatalf("Failed to unset %s: %v", name, err)
+	}
+}
@@ -0,0 +1,305 @@
+package testutil
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Screen emulates a fixed size terminal so output that redraws itself, such
+// as progress bars and spinners, can be checked as a user would see it.
+// Carriage returns, backspaces, tabs, line wrapping, scrolling and the common
+// cursor movement and erase sequences are supported; other escape sequences,
+// including SGR styling, are ignored. Every rune occupies a single cell.
+//
+// Screen implements io.Writer and records a frame after every write.
+type Screen struct {
+	width   int
+	height  int
+	cells   [][]rune
+	row     int
+	col     int
+	saveRow int
+	saveCol int
+	// wrap is set when the last column was written so the next rune wraps
+	wrap    bool
+	pending []byte
+	frames  [][]string
+}
+
+// NewScreen returns a blank screen of width columns and height rows
+func NewScreen(width, height int) *Screen {
+	if width < 1 || height < 1 {
+		panic("testutil.NewScreen: width and height must be at least 1")
+	}
+	s := &Screen{
+		width:  width,
+		height: height,
+	}
+	s.cells = make([][]rune, height)
+	for i := range s.cells {
+		s.cells[i] = s.blankRow()
+	}
+	return s
+}
+
+// Screen replays the writer's stdout and stderr, in the order they were
+// written, onto a new screen of the given size. Each Printf or Errorf call
+// becomes one frame.
+func (w *BufferedWriter) Screen(width, height int) *Screen {
+	s := NewScreen(width, height)
+	for _, e := range w.Timeline() {
+		_, _ = s.Write([]byte(e.Text))
+	}
+	return s
+}
+
+func (s *Screen) blankRow() []rune {
+	row := make([]rune, s.width)
+	for i := range row {
+		row[i] = ' '
+	}
+	return row
+}
+
+// Write interprets p as terminal output. Escape sequences and runes split
+// across writes are held until the rest arrives.
+func (s *Screen) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	s.pending = s.pending[s.process(s.pending):]
+	s.frames = append(s.frames, s.Lines())
+	return len(p), nil
+}
+
+// process interprets data and returns the number of bytes consumed
+func (s *Screen) process(data []byte) (i int) {
+	for i < len(data) {
+		c := data[i]
+		switch {
+		case c == 0x1b:
+			n := s.escape(data[i:])
+			if n == 0 {
+				return i
+			}
+			i += n
+			continue
+		case c == '\n':
+			s.lineFeed()
+			s.col = 0
+		case c == '\r':
+			s.col, s.wrap = 0, false
+		case c == '\b':
+			if s.col > 0 {
+				s.col--
+			}
+			s.wrap = false
+		case c == '\t':
+			s.col = min((s.col/8+1)*8, s.width-1)
+		case c < 0x20 || c == 0x7f:
+		default:
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			r, n := utf8.DecodeRune(data[i:])
+			s.put(r)
+			i += n
+			continue
+		}
+		i++
+	}
+	return i
+}
+
+func (s *Screen) put(r rune) {
+	if s.wrap {
+		s.lineFeed()
+		s.col = 0
+	}
+	s.cells[s.row][s.col] = r
+	if s.col == s.width-1 {
+		s.wrap = true
+		return
+	}
+	s.col++
+}
+
+// lineFeed moves the cursor down a row, scrolling at the bottom of the screen
+func (s *Screen) lineFeed() {
+	s.wrap = false
+	if s.row < s.height-1 {
+		s.row++
+		return
+	}
+	s.cells = append(s.cells[1:], s.blankRow())
+}
+
+// escape handles the escape sequence at the start of data and returns its
+// length, or 0 if the sequence is incomplete
+func (s *Screen) escape(data []byte) (n int) {
+	if len(data) < 2 {
+		goto end
+	}
+	switch data[1] {
+	case '[':
+		n = s.csi(data)
+	case ']':
+		for i := 2; i < len(data); i++ {
+			if data[i] == 0x07 {
+				n = i + 1
+				break
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				n = i + 2
+				break
+			}
+		}
+	case '7':
+		s.saveRow, s.saveCol = s.row, s.col
+		n = 2
+	case '8':
+		s.row, s.col, s.wrap = s.saveRow, s.saveCol, false
+		n = 2
+	default:
+		n = 2
+	}
+end:
+	return n
+}
+
+// csi handles a control sequence, returning its length or 0 if incomplete
+func (s *Screen) csi(data []byte) (n int) {
+	var params string
+	var final byte
+	var i int
+
+	i = 2
+	for i < len(data) && data[i] >= 0x20 && data[i] <= 0x3f {
+		i++
+	}
+	if i == len(data) {
+		goto end
+	}
+	final = data[i]
+	n = i + 1
+	if final < 0x40 || final > 0x7e {
+		// Malformed; drop the escape and let the rest print
+		n = 1
+		goto end
+	}
+	params = string(data[2:i])
+	s.control(final, params)
+end:
+	return n
+}
+
+// control performs the cursor movement or erase for a control sequence
+func (s *Screen) control(final byte, params string) {
+	var args []int
+
+	for _, p := range strings.Split(params, ";") {
+		n, _ := strconv.Atoi(p)
+		args = append(args, n)
+	}
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+	s.wrap = false
+	switch final {
+	case 'A':
+		s.row = max(s.row-arg(0, 1), 0)
+	case 'B':
+		s.row = min(s.row+arg(0, 1), s.height-1)
+	case 'C':
+		s.col = min(s.col+arg(0, 1), s.width-1)
+	case 'D':
+		s.col = max(s.col-arg(0, 1), 0)
+	case 'E':
+		s.row, s.col = min(s.row+arg(0, 1), s.height-1), 0
+	case 'F':
+		s.row, s.col = max(s.row-arg(0, 1), 0), 0
+	case 'G':
+		s.col = min(arg(0, 1), s.width) - 1
+	case 'H', 'f':
+		s.row = min(arg(0, 1), s.height) - 1
+		s.col = min(arg(1, 1), s.width) - 1
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(s.row, arg(0, 0))
+	case 's':
+		s.saveRow, s.saveCol = s.row, s.col
+	case 'u':
+		s.row, s.col = s.saveRow, s.saveCol
+	}
+}
+
+// eraseLine erases from the cursor to the end of row (mode 0), from the start
+// of row to the cursor (mode 1) or the whole row (mode 2)
+func (s *Screen) eraseLine(row, mode int) {
+	from, to := s.col, s.width
+	switch mode {
+	case 1:
+		from, to = 0, s.col+1
+	case 2:
+		from = 0
+	}
+	for i := from; i < to; i++ {
+		s.cells[row][i] = ' '
+	}
+}
+
+// eraseDisplay erases from the cursor to the end of the screen (mode 0), from
+// the start of the screen to the cursor (mode 1) or the whole screen (mode 2)
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(s.row, 0)
+		for r := s.row + 1; r < s.height; r++ {
+			s.cells[r] = s.blankRow()
+		}
+	case 1:
+		s.eraseLine(s.row, 1)
+		for r := 0; r < s.row; r++ {
+			s.cells[r] = s.blankRow()
+		}
+	default:
+		for r := range s.cells {
+			s.cells[r] = s.blankRow()
+		}
+	}
+}
+
+// Lines returns every row of the screen with trailing spaces removed
+func (s *Screen) Lines() []string {
+	lines := make([]string, s.height)
+	for i, row := range s.cells {
+		lines[i] = strings.TrimRight(string(row), " ")
+	}
+	return lines
+}
+
+// String returns the screen's rows joined by newlines, omitting trailing
+// blank rows
+func (s *Screen) String() string {
+	lines := s.Lines()
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Cursor returns the zero-based row and column of the cursor
+func (s *Screen) Cursor() (row, col int) {
+	return s.row, s.col
+}
+
+// Frames returns the screen's lines as they were after each write
+func (s *Screen) Frames() [][]string {
+	return s.frames
+}
@@ -0,0 +1,439 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+)
+
+// ScriptArgs configures RunScripts and RunScript
+type ScriptArgs struct {
+	// Dir holds the *.txtar scripts; defaults to "testdata"
+	Dir dt.DirPath
+	// Commands maps script command names to constructors for the go-cliutil
+	// commands they run. A new command is constructed for every line so flag
+	// values never leak between lines.
+	Commands map[string]func() cliutil.Command
+}
+
+// RunScripts runs every *.txtar script in args.Dir as a subtest.
+//
+// Each script's comment section holds one command per line and its files are
+// written to a fresh temp directory the script starts in. A line starting with
+// "!" must fail. Lines run either a command from args.Commands, captured in
+// process with RunCLIWithArgs, or one of these built-ins:
+//
+//	cd dir             change the script's working directory
+//	env KEY=VALUE...   set environment variables for later commands
+//	exists file...     check that the files exist
+//	cmp file1 file2    compare files; "stdout" and "stderr" name the last output
+//	stdout regexp      check the last command's stdout matches
+//	stderr regexp      check the last command's stderr matches
+//	exitcode N         check the last command's exit code
+//
+// Arguments may be single-quoted and may reference $WORK, the script's temp
+// directory, and any variables set with env. The environment, including WORK,
+// and the working directory are set only while each command runs. As commands
+// run in process, script tests must not call t.Parallel.
+func RunScripts(t *testing.T, args *ScriptArgs) {
+	var files []string
+	var err error
+
+	t.Helper()
+	if args == nil {
+		args = &ScriptArgs{}
+	}
+	dir := args.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	files, err = filepath.Glob(filepath.Join(string(dir), "*.txtar"))
+	if err != nil {
+		t.Fatalf("Failed to find scripts in %s: %v", dir, err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("No *.txtar scripts found in %s", dir)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".txtar")
+		t.Run(name, func(t *testing.T) {
+			RunScript(t, dt.Filepath(file), args)
+		})
+	}
+}
+
+// RunScript runs a single txtar script; see RunScripts for the script format
+func RunScript(t *testing.T, file dt.Filepath, args *ScriptArgs) {
+	t.Helper()
+	if args == nil {
+		args = &ScriptArgs{}
+	}
+	s := &scriptState{
+		t:    t,
+		file: file,
+		args: args,
+		work: dt.DirPath(t.TempDir()),
+	}
+	s.cwd = s.work
+	s.env = map[string]string{"WORK": string(s.work)}
+	s.run(ParseTxtar(LoadFile(t, file, true)))
+}
+
+// scriptState is the state of a running script
+type scriptState struct {
+	t          *testing.T
+	file       dt.Filepath
+	args       *ScriptArgs
+	work       dt.DirPath
+	cwd        dt.DirPath
+	env        map[string]string
+	line       int
+	last       *CLIResult
+	transcript strings.Builder
+}
+
+func (s *scriptState) run(a *TxtarArchive) {
+	var err error
+
+	WriteTree(s.t, s.work, a)
+
+	for i, line := range strings.Split(string(a.Comment), "\n") {
+		s.line = i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s.transcript.WriteString("> " + line + "\n")
+		err = s.exec(line)
+		if err != nil {
+			s.t.Fatalf("%s\nFAIL: %s:%d: %v", strings.TrimRight(s.transcript.String(), "\n"), s.file, s.line, err)
+		}
+	}
+}
+
+// exec runs a single script line
+func (s *scriptState) exec(line string) (err error) {
+	var words []string
+	var neg bool
+
+	if strings.HasPrefix(line, "!") {
+		neg = true
+		line = strings.TrimSpace(line[1:])
+	}
+	words, err = s.parseWords(line)
+	if err != nil {
+		goto end
+	}
+	if len(words) == 0 {
+		err = fmt.Errorf("missing command after '!'")
+		goto end
+	}
+
+	switch words[0] {
+	case "cd":
+		err = s.cd(neg, words[1:])
+	case "env":
+		err = s.setEnv(neg, words[1:])
+	case "exists":
+		err = s.exists(neg, words[1:])
+	case "cmp":
+		err = s.cmp(neg, words[1:])
+	case "stdout", "stderr":
+		err = s.match(neg, words[0], words[1:])
+	case "exitcode":
+		err = s.exitCode(neg, words[1:])
+	default:
+		err = s.runCommand(neg, words[0], words[1:])
+	}
+end:
+	return err
+}
+
+func (s *scriptState) runCommand(neg bool, name string, args []string) (err error) {
+	newCmd, ok := s.args.Commands[name]
+	if !ok {
+		err = fmt.Errorf("unknown command %q", name)
+		goto end
+	}
+	err = s.scoped(func() {
+		s.last = RunCLIWithArgs(s.t, newCmd(), &CLIRunArgs{Args: args})
+	})
+	if err != nil {
+		goto end
+	}
+	if out := s.last.Stdout(); out != "" {
+		s.transcript.WriteString("[stdout]\n" + out)
+	}
+	if out := s.last.Stderr(); out != "" {
+		s.transcript.WriteString("[stderr]\n" + out)
+	}
+	if s.last.Err != nil {
+		fmt.Fprintf(&s.transcript, "[exit %d] %v\n", s.last.ExitCode, s.last.Err)
+	}
+	switch {
+	case neg && s.last.ExitCode == 0:
+		err = fmt.Errorf("unexpected success of %s", name)
+	case !neg && s.last.ExitCode != 0:
+		err = fmt.Errorf("unexpected failure of %s: %v", name, s.last.Err)
+	}
+end:
+	return err
+}
+
+// scoped runs fn with the script's environment and working directory,
+// restoring the process's own afterwards. CLIRunArgs.Env and Dir would instead
+// last until the test ends.
+func (s *scriptState) scoped(fn func()) (err error) {
+	var wd string
+
+	wd, err = os.Getwd()
+	if err != nil {
+		goto end
+	}
+	for key, value := range s.env {
+		old, ok := os.LookupEnv(key)
+		err = os.Setenv(key, value)
+		if err != nil {
+			goto end
+		}
+		defer func() {
+			if ok {
+				_ = os.Setenv(key, old)
+				return
+			}
+			_ = os.Unsetenv(key)
+		}()
+	}
+	err = os.Chdir(string(s.cwd))
+	if err != nil {
+		goto end
+	}
+	defer func() {
+		_ = os.Chdir(wd)
+	}()
+	fn()
+end:
+	return err
+}
+
+func (s *scriptState) cd(neg bool, args []string) (err error) {
+	var dir dt.DirPath
+	var info os.FileInfo
+
+	if neg || len(args) != 1 {
+		err = fmt.Errorf("usage: cd dir")
+		goto end
+	}
+	dir = dt.DirPath(s.path(args[0]))
+	info, err = dir.Stat()
+	if err != nil {
+		goto end
+	}
+	if !info.IsDir() {
+		err = fmt.Errorf("%s is not a directory", args[0])
+		goto end
+	}
+	s.cwd = dir
+end:
+	return err
+}
+
+func (s *scriptState) setEnv(neg bool, args []string) (err error) {
+	if neg || len(args) == 0 {
+		err = fmt.Errorf("usage: env KEY=VALUE...")
+		goto end
+	}
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found {
+			err = fmt.Errorf("env %s: expected KEY=VALUE", arg)
+			goto end
+		}
+		s.env[key] = value
+	}
+end:
+	return err
+}
+
+func (s *scriptState) exists(neg bool, args []string) (err error) {
+	if len(args) == 0 {
+		err = fmt.Errorf("usage: exists file...")
+		goto end
+	}
+	for _, arg := range args {
+		_, statErr := s.path(arg).Stat()
+		switch {
+		case !neg && statErr != nil:
+			err = fmt.Errorf("%s does not exist", arg)
+		case neg && statErr == nil:
+			err = fmt.Errorf("%s unexpectedly exists", arg)
+		}
+		if err != nil {
+			goto end
+		}
+	}
+end:
+	return err
+}
+
+func (s *scriptState) cmp(neg bool, args []string) (err error) {
+	var got, want []byte
+
+	if len(args) != 2 {
+		err = fmt.Errorf("usage: cmp file1 file2")
+		goto end
+	}
+	got, err = s.readFile(args[0])
+	if err != nil {
+		goto end
+	}
+	want, err = s.readFile(args[1])
+	if err != nil {
+		goto end
+	}
+	switch {
+	case !neg && !bytes.Equal(got, want):
+		err = fmt.Errorf("%s and %s differ\n--- %s\n%s--- %s\n%s", args[0], args[1], args[0], got, args[1], want)
+	case neg && bytes.Equal(got, want):
+		err = fmt.Errorf("%s and %s are unexpectedly identical", args[0], args[1])
+	}
+end:
+	return err
+}
+
+func (s *scriptState) match(neg bool, stream string, args []string) (err error) {
+	var re *regexp.Regexp
+	var text []byte
+
+	if len(args) != 1 {
+		err = fmt.Errorf("usage: %s regexp", stream)
+		goto end
+	}
+	re, err = regexp.Compile(`(?m)` + args[0])
+	if err != nil {
+		goto end
+	}
+	text, err = s.readFile(stream)
+	if err != nil {
+		goto end
+	}
+	switch {
+	case !neg && !re.Match(text):
+		err = fmt.Errorf("no match for %#q in %s", args[0], stream)
+	case neg && re.Match(text):
+		err = fmt.Errorf("unexpected match for %#q in %s", args[0], stream)
+	}
+end:
+	return err
+}
+
+func (s *scriptState) exitCode(neg bool, args []string) (err error) {
+	var code int
+
+	if neg || len(args) != 1 {
+		err = fmt.Errorf("usage: exitcode N")
+		goto end
+	}
+	if s.last == nil {
+		err = fmt.Errorf("exitcode used before any command was run")
+		goto end
+	}
+	code, err = strconv.Atoi(args[0])
+	if err != nil {
+		goto end
+	}
+	if s.last.ExitCode != code {
+		err = fmt.Errorf("expected exit code %d, got %d", code, s.last.ExitCode)
+	}
+end:
+	return err
+}
+
+// readFile returns the contents of a script file, or of the last command's
+// stdout or stderr
+func (s *scriptState) readFile(name string) (data []byte, err error) {
+	switch name {
+	case "stdout", "stderr":
+		if s.last == nil {
+			err = fmt.Errorf("%s used before any command was run", name)
+			goto end
+		}
+		data = []byte(s.last.Stdout())
+		if name == "stderr" {
+			data = []byte(s.last.Stderr())
+		}
+	default:
+		data, err = s.path(name).ReadFile()
+	}
+end:
+	return data, err
+}
+
+// path resolves name relative to the script's working directory
+func (s *scriptState) path(name string) dt.Filepath {
+	if filepath.IsAbs(name) {
+		return dt.Filepath(filepath.Clean(name))
+	}
+	return dt.FilepathJoin(s.cwd, name)
+}
+
+// parseWords splits a script line into words, honoring single quotes and
+// expanding $VAR and ${VAR} references outside of them
+func (s *scriptState) parseWords(line string) (words []string, err error) {
+	var word strings.Builder
+	var inWord, quoted bool
+
+	expand := func(v string) string {
+		return os.Expand(v, func(key string) string {
+			return s.env[key]
+		})
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quoted && c == '\'':
+			if i+1 < len(line) && line[i+1] == '\'' {
+				word.WriteByte('\'')
+				i++
+				continue
+			}
+			quoted = false
+		case quoted:
+			word.WriteByte(c)
+		case c == '\'':
+			quoted, inWord = true, true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '$':
+			j := i + 1
+			for j < len(line) && line[j] != ' ' && line[j] != '\t' && line[j] != '\'' {
+				j++
+			}
+			word.WriteString(expand(line[i:j]))
+			inWord = true
+			i = j - 1
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quoted {
+		err = fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, err
+}
@@ -0,0 +1,267 @@
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultPromptTimeout is how long ScriptedInput waits for each prompt
+const DefaultPromptTimeout = 5 * time.Second
+
+// promptSettle is how long an unexpected prompt must stay unchanged before
+// ScriptedInput fails, so a prompt still being written is not mistaken for one
+const promptSettle = 100 * time.Millisecond
+
+// ErrUnexpectedPrompt is returned by ScriptedInput.Read when the program asks
+// for input other than the expected prompt
+var ErrUnexpectedPrompt = errors.New("unexpected prompt")
+
+// ScriptedInput is an io.Reader that drives a program's prompts, e.g. when
+// passed as CLIRunArgs.Stdin. Each Read waits for the next expected prompt to
+// appear on the writer's stdout and then returns the response for it.
+//
+// The test fails with a transcript of the conversation so far, and Read
+// returns an error, when the program asks something unexpected: when it waits
+// for input after printing a partial line that does not match the expected
+// prompt, when it prompts again after every response was sent, or when the
+// expected prompt does not appear before the timeout. The test also fails if
+// it ends before every prompt was seen.
+//
+// A read is usually made by a goroutine copying into the program's stdin pipe
+// rather than by the program itself, so the partial line the program leaves
+// when it waits for input is what counts as its prompt. RunCLIWithArgs and
+// StartBinary stop the input when the program exits so that output it leaves
+// without a final newline is not mistaken for a prompt.
+type ScriptedInput struct {
+	t       *testing.T
+	w       *BufferedWriter
+	timeout time.Duration
+	steps   []promptStep
+	// pending holds the part of the current response not yet read
+	pending    []byte
+	pos        int
+	transcript strings.Builder
+	done       chan struct{}
+	closed     bool
+	allowEOF   bool
+	mu         sync.Mutex
+}
+
+type promptStep struct {
+	prompt   *regexp.Regexp
+	response string
+}
+
+// NewScriptedInput returns a ScriptedInput that watches the stdout of w, which
+// must be the writer the program prints its prompts to
+func NewScriptedInput(t *testing.T, w *BufferedWriter) *ScriptedInput {
+	si := &ScriptedInput{
+		t:       t,
+		w:       w,
+		timeout: DefaultPromptTimeout,
+		done:    make(chan struct{}),
+	}
+	t.Cleanup(si.cleanup)
+	return si
+}
+
+// Expect adds a step that waits for stdout to match the prompt regexp and
+// then sends response. A newline is not added to response.
+func (si *ScriptedInput) Expect(prompt string, response string) *ScriptedInput {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.steps = append(si.steps, promptStep{
+		prompt:   regexp.MustCompile(prompt),
+		response: response,
+	})
+	return si
+}
+
+// AllowEOF lets the program read after every response was sent, returning
+// io.EOF instead of failing the test, for programs that read until EOF
+func (si *ScriptedInput) AllowEOF() *ScriptedInput {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.allowEOF = true
+	return si
+}
+
+// SetTimeout sets how long to wait for each prompt
+func (si *ScriptedInput) SetTimeout(d time.Duration) *ScriptedInput {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.timeout = d
+	return si
+}
+
+// Read returns the response to the next prompt once it appears
+func (si *ScriptedInput) Read(p []byte) (n int, err error) {
+	var step promptStep
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	if len(si.pending) > 0 {
+		goto read
+	}
+	if si.closed {
+		err = io.EOF
+		goto end
+	}
+	if len(si.steps) == 0 {
+		if !si.allowEOF {
+			// Fails the test if the program prompts again
+			_ = si.waitFor(nil)
+		}
+		err = io.EOF
+		goto end
+	}
+	step = si.steps[0]
+	err = si.waitFor(step.prompt)
+	if err != nil {
+		goto end
+	}
+	si.steps = si.steps[1:]
+	si.pending = []byte(step.response)
+	fmt.Fprintf(&si.transcript, "[sent %q]\n", step.response)
+read:
+	n = copy(p, si.pending)
+	si.pending = si.pending[n:]
+end:
+	return n, err
+}
+
+// waitFor polls stdout for prompt, unlocking si.mu while it sleeps. It fails
+// early if the unread output ends in a partial line that does not match, as
+// that is the program waiting on a prompt the script does not expect. A nil
+// prompt is used after the script ends: it only fails on such a partial line
+// and otherwise returns io.EOF at the timeout. The caller holds si.mu.
+func (si *ScriptedInput) waitFor(prompt *regexp.Regexp) (err error) {
+	var unread, partial, lastPartial string
+	var loc []int
+	var settled time.Time
+
+	deadline := time.Now().Add(si.timeout)
+	for {
+		unread = si.unreadLocked()
+		partial = unread[strings.LastIndexByte(unread, '\n')+1:]
+		loc = nil
+		if prompt != nil {
+			loc = prompt.FindStringIndex(unread)
+		}
+		switch {
+		case loc == nil:
+		case !strings.Contains(unread[loc[1]:], "\n"):
+			// The rest of the partial line is part of the prompt answered
+			si.transcript.WriteString(unread)
+			si.pos += len(unread)
+			goto end
+		case partial == "":
+			si.transcript.WriteString(unread[:loc[1]])
+			si.pos += loc[1]
+			goto end
+		}
+		// Otherwise a match on an earlier line does not count when a later
+		// partial line is the prompt the program is waiting on
+		if si.closed {
+			err = io.EOF
+			goto end
+		}
+		switch {
+		case partial == "":
+		case partial != lastPartial:
+			lastPartial = partial
+			settled = time.Now().Add(min(promptSettle, si.timeout))
+		case prompt == nil && time.Now().After(settled):
+			err = fmt.Errorf("%w %q after the script ended", ErrUnexpectedPrompt, partial)
+			goto fail
+		case time.Now().After(settled):
+			err = fmt.Errorf("%w %q, expected %#q", ErrUnexpectedPrompt, partial, prompt)
+			goto fail
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		si.mu.Unlock()
+		select {
+		case <-si.done:
+		case <-time.After(5 * time.Millisecond):
+		}
+		si.mu.Lock()
+	}
+	if prompt == nil {
+		err = io.EOF
+		goto end
+	}
+	err = fmt.Errorf("%w: %#q did not appear within %s", ErrUnexpectedPrompt, prompt, si.timeout)
+fail:
+	si.t.Errorf("Scripted input failed: %v\n%s", err, si.transcriptWith(unread))
+end:
+	return err
+}
+
+// Transcript returns the conversation so far: the program's stdout with each
+// response sent shown where it was sent
+func (si *ScriptedInput) Transcript() string {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	return si.transcript.String()
+}
+
+func (si *ScriptedInput) transcriptWith(unmatched string) string {
+	var sb strings.Builder
+	sb.WriteString("--- transcript ---\n")
+	sb.WriteString(si.transcript.String())
+	if unmatched != "" {
+		sb.WriteString(unmatched)
+		if !strings.HasSuffix(unmatched, "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	sb.WriteString("--- end ---")
+	return sb.String()
+}
+
+// stopInput ends the input once the program has exited, so any Read still
+// waiting returns io.EOF
+func (si *ScriptedInput) stopInput() {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.closeLocked()
+}
+
+func (si *ScriptedInput) closeLocked() {
+	if !si.closed {
+		si.closed = true
+		close(si.done)
+	}
+}
+
+// cleanup stops any Read still waiting and fails the test if prompts were
+// never seen
+func (si *ScriptedInput) cleanup() {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.closeLocked()
+	if len(si.steps) == 0 {
+		return
+	}
+	si.t.Errorf("Scripted input ended with %d prompt(s) never seen, next was %#q\n%s",
+		len(si.steps), si.steps[0].prompt, si.transcriptWith(si.unreadLocked()))
+}
+
+// unreadLocked returns the stdout not yet matched against a prompt. If the
+// writer was Reset so stdout is now shorter than what was already read, the
+// output is read again from the start. The caller holds si.mu.
+func (si *ScriptedInput) unreadLocked() string {
+	out := si.w.GetStdout()
+	if si.pos > len(out) {
+		si.pos = 0
+	}
+	return out[si.pos:]
+}
@@ -0,0 +1,187 @@
+package testutil
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SecretDetector finds one kind of secret in captured text
+type SecretDetector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate optionally confirms a pattern match, e.g. a Luhn check for card
+	// numbers. A nil Validate accepts every match.
+	Validate func(match string) bool
+}
+
+// SecretFinding describes a secret found in captured text
+type SecretFinding struct {
+	Detector string
+	Location string
+	Match    string
+}
+
+// String returns the finding with the matched secret redacted
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s found in %s: %s", f.Detector, f.Location, redactSecret(f.Match))
+}
+
+// redactSecret keeps just enough of a secret to help find it in the output
+func redactSecret(s string) string {
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return fmt.Sprintf("%s...(%d chars)", s[:4], len(s))
+}
+
+var (
+	bearerTokenPattern  = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*`)
+	awsAccessKeyPattern = regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`)
+	awsSecretKeyPattern = regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[=:]\s*"?[A-Za-z0-9/+=]{40}`)
+	emailPattern        = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)
+	// Card numbers are either grouped with consistent separators or are bare
+	// digits with a known issuer prefix and length, so that timestamps and
+	// numeric IDs that happen to pass the Luhn check are not reported
+	creditCardPattern = regexp.MustCompile(`\b(?:` +
+		`\d{4}-\d{4}-\d{4}-\d{4}|\d{4} \d{4} \d{4} \d{4}|` + // 16 digits in fours
+		`\d{4}-\d{6}-\d{5}|\d{4} \d{6} \d{5}|` + // 15 digit American Express
+		`4\d{12}(?:\d{3}){0,2}|` + // Visa
+		`(?:5[1-5]\d{2}|2[2-7]\d{2})\d{12}|` + // Mastercard
+		`3[47]\d{13}|` + // American Express
+		`6(?:011|5\d{2})\d{12,15}` + // Discover
+		`)\b`)
+	jwtPattern = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+)
+
+// DefaultSecretDetectors returns the built-in detectors for bearer tokens,
+// AWS-style keys, email addresses, credit card numbers and JWTs
+func DefaultSecretDetectors() []SecretDetector {
+	return []SecretDetector{
+		{Name: "bearer_token", Pattern: bearerTokenPattern},
+		{Name: "aws_access_key", Pattern: awsAccessKeyPattern},
+		{Name: "aws_secret_key", Pattern: awsSecretKeyPattern},
+		{Name: "email", Pattern: emailPattern},
+		{Name: "credit_card", Pattern: creditCardPattern, Validate: luhnValid},
+		{Name: "jwt", Pattern: jwtPattern},
+	}
+}
+
+// luhnValid returns true if the digits in s pass the Luhn checksum
+func luhnValid(s string) bool {
+	var sum, count int
+	var double bool
+
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		count++
+		double = !double
+	}
+	return count >= 13 && sum%10 == 0
+}
+
+// SecretScanner checks captured output for secrets and personal data
+type SecretScanner struct {
+	detectors []SecretDetector
+	mu        sync.Mutex
+}
+
+// NewSecretScanner creates a SecretScanner using the default detectors
+func NewSecretScanner() *SecretScanner {
+	return &SecretScanner{
+		detectors: DefaultSecretDetectors(),
+	}
+}
+
+// AddDetector adds a custom detector
+func (s *SecretScanner) AddDetector(d SecretDetector) *SecretScanner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.detectors = append(s.detectors, d)
+	return s
+}
+
+// AddPattern adds a custom detector for the given regular expression
+func (s *SecretScanner) AddPattern(name string, pattern *regexp.Regexp) *SecretScanner {
+	return s.AddDetector(SecretDetector{Name: name, Pattern: pattern})
+}
+
+// RemoveDetector removes the named detector, e.g. "email" for tests where
+// email addresses are expected in the output
+func (s *SecretScanner) RemoveDetector(name string) *SecretScanner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.detectors = slices.DeleteFunc(s.detectors, func(d SecretDetector) bool {
+		return d.Name == name
+	})
+	return s
+}
+
+// ScanText returns the findings for a single piece of captured text
+func (s *SecretScanner) ScanText(ct CapturedText) (findings []SecretFinding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.detectors {
+		for _, match := range d.Pattern.FindAllString(ct.Text, -1) {
+			if d.Validate != nil && !d.Validate(match) {
+				continue
+			}
+			findings = append(findings, SecretFinding{
+				Detector: d.Name,
+				Location: ct.Location,
+				Match:    match,
+			})
+		}
+	}
+	return findings
+}
+
+// Scan returns the findings for all text captured by the given sources
+func (s *SecretScanner) Scan(sources ...CapturedTextSource) (findings []SecretFinding) {
+	for _, source := range sources {
+		for _, ct := range source.CapturedText() {
+			findings = append(findings, s.ScanText(ct)...)
+		}
+	}
+	return findings
+}
+
+// Check fails the test once for each secret found in the given sources
+func (s *SecretScanner) Check(t *testing.T, sources ...CapturedTextSource) {
+	t.Helper()
+	for _, f := range s.Scan(sources...) {
+		t.Errorf("Secret detected: %s", f)
+	}
+}
+
+// AssertNoSecrets registers a cleanup that scans the given sources once the
+// test completes and fails the test for every secret found. At least one
+// source is required; the package-level buffered log handler is shared by
+// every test so it is not scanned by default. The returned scanner can be
+// customized before the test ends.
+func AssertNoSecrets(t *testing.T, sources ...CapturedTextSource) *SecretScanner {
+	t.Helper()
+	if len(sources) == 0 {
+		t.Fatal("AssertNoSecrets requires at least one source to scan")
+	}
+	s := NewSecretScanner()
+	t.Cleanup(func() {
+		s.Check(t, sources...)
+	})
+	return s
+}
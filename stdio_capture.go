@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+// activeStdioCapture guards against nested or parallel calls to CaptureStdio,
+// which redirects process-wide state
+var (
+	activeStdioCapture   *StdioCapture
+	activeStdioCaptureMu sync.Mutex
+)
+
+// StdioCapture captures everything written to the process's stdout and stderr
+// into a BufferedWriter, including writes that bypass cliutil.Writer such as
+// fmt.Println or output from third-party libraries
+type StdioCapture struct {
+	*BufferedWriter
+	restores []func() error
+	pipes    []*os.File
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// CaptureStdio redirects stdout and stderr through pipes until Stop is called
+// or the test ends, even if it panics. Where the platform allows, the file
+// descriptors themselves are redirected so output from cgo or child processes
+// that inherit them is captured too; elsewhere os.Stdout and os.Stderr are
+// replaced.
+//
+// Call Stop before reading the captured output so everything written has been
+// collected. Because stdout and stderr are process-wide, tests that capture
+// them must not run in parallel.
+func CaptureStdio(t *testing.T) (c *StdioCapture) {
+	var err error
+
+	t.Helper()
+	c = &StdioCapture{BufferedWriter: NewBufferedWriter()}
+	activeStdioCaptureMu.Lock()
+	if activeStdioCapture != nil {
+		activeStdioCaptureMu.Unlock()
+		t.Fatalf("CaptureStdio called while stdio is already being captured")
+	}
+	activeStdioCapture = c
+	// Stop locks activeStdioCaptureMu so it must be unlocked before any
+	// failure below calls Stop
+	activeStdioCaptureMu.Unlock()
+	t.Cleanup(c.Stop)
+
+	err = c.capture(&os.Stdout, 1, c.Writer())
+	if err == nil {
+		err = c.capture(&os.Stderr, 2, c.ErrWriter())
+	}
+	if err != nil {
+		c.Stop()
+		t.Fatalf("Failed to capture stdio: %v", err)
+	}
+	return c
+}
+
+// capture redirects one stream into a pipe whose contents are copied to w
+func (c *StdioCapture) capture(f **os.File, fd int, w io.Writer) (err error) {
+	var restore func() error
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		goto end
+	}
+	restore, err = redirectStdio(f, fd, pw)
+	if err != nil {
+		_ = pr.Close()
+		_ = pw.Close()
+		goto end
+	}
+	c.restores = append(c.restores, restore)
+	c.pipes = append(c.pipes, pw)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		_, _ = io.Copy(w, pr)
+		_ = pr.Close()
+	}()
+end:
+	return err
+}
+
+// Stop restores stdout and stderr and waits for everything written to them to
+// be collected. It is safe to call more than once.
+func (c *StdioCapture) Stop() {
+	c.once.Do(func() {
+		for _, restore := range c.restores {
+			_ = restore()
+		}
+		for _, pw := range c.pipes {
+			_ = pw.Close()
+		}
+		c.wg.Wait()
+		activeStdioCaptureMu.Lock()
+		if activeStdioCapture == c {
+			activeStdioCapture = nil
+		}
+		activeStdioCaptureMu.Unlock()
+	})
+}
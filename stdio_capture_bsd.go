@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package testutil
+
+import "syscall"
+
+// dupTo makes newfd a copy of oldfd
+func dupTo(oldfd, newfd int) error {
+	return syscall.Dup2(oldfd, newfd)
+}
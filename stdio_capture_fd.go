@@ -0,0 +1,31 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package testutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// redirectStdio points file descriptor fd, which *f writes to, at pw and
+// returns a func that points it back
+func redirectStdio(f **os.File, fd int, pw *os.File) (restore func() error, err error) {
+	var saved int
+
+	saved, err = syscall.Dup(fd)
+	if err != nil {
+		goto end
+	}
+	err = dupTo(int(pw.Fd()), fd)
+	if err != nil {
+		_ = syscall.Close(saved)
+		goto end
+	}
+	restore = func() (err error) {
+		err = dupTo(saved, fd)
+		_ = syscall.Close(saved)
+		return err
+	}
+end:
+	return restore, err
+}
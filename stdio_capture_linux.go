@@ -0,0 +1,9 @@
+package testutil
+
+import "syscall"
+
+// dupTo makes newfd a copy of oldfd. Dup2 is unavailable on some Linux
+// architectures so Dup3 is used.
+func dupTo(oldfd, newfd int) error {
+	return syscall.Dup3(oldfd, newfd, 0)
+}
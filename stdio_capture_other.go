@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package testutil
+
+import "os"
+
+// redirectStdio replaces *f with pw and returns a func that puts the original
+// back. The underlying file descriptor is left alone.
+func redirectStdio(f **os.File, _ int, pw *os.File) (restore func() error, err error) {
+	orig := *f
+	*f = pw
+	restore = func() error {
+		*f = orig
+		return nil
+	}
+	return restore, err
+}
@@ -0,0 +1,286 @@
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrNoTable is returned by ParseTable when the text contains no table
+var ErrNoTable = errors.New("no table found")
+
+// Table is tabular output parsed by ParseTable
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// TableRow is a single row of a Table
+type TableRow struct {
+	table *Table
+	Cells []string
+}
+
+// tableHeaderName matches a column name in the header of an aligned table;
+// names may contain single spaces
+var tableHeaderName = regexp.MustCompile(`\S+(?: \S+)*`)
+
+// ParseTable parses the first table in s, which is either a Markdown-style
+// table with "|" separated cells or an aligned table such as text/tabwriter
+// prints. Blank lines before the table are skipped and the table ends at the
+// next blank line. A rule of dashes or similar characters is ignored above
+// the header and directly beneath it; elsewhere such a line is a row, as cells
+// of "-" are common placeholders for missing values.
+//
+// Columns of an aligned table start where the header's columns start, so
+// padding may change without affecting the parsed cells. Header names may
+// contain single spaces, as in "LAST SEEN"; columns must be separated by at
+// least two spaces or a tab.
+func ParseTable(s string) (table *Table, err error) {
+	var lines []string
+
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		if len(lines) < 2 && isTableRule(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		err = ErrNoTable
+		goto end
+	}
+	if strings.HasPrefix(strings.TrimSpace(lines[0]), "|") {
+		table, err = parseMarkdownTable(lines)
+		goto end
+	}
+	table = parseAlignedTable(lines)
+end:
+	return table, err
+}
+
+// isTableRule returns true for separator lines such as "-----  ---" or
+// "|---|:---:|", where every cell has at least three dashes or equals signs
+func isTableRule(line string) bool {
+	if strings.Trim(line, "-=+|: \t") != "" {
+		return false
+	}
+	cells := strings.FieldsFunc(line, func(r rune) bool {
+		return strings.ContainsRune("+| \t", r)
+	})
+	for _, cell := range cells {
+		if strings.Count(cell, "-")+strings.Count(cell, "=") < 3 {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+func parseMarkdownTable(lines []string) (table *Table, err error) {
+	table = &Table{}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "|")
+		line = strings.TrimSuffix(line, "|")
+		cells := strings.Split(line, "|")
+		for j := range cells {
+			cells[j] = strings.TrimSpace(cells[j])
+		}
+		if i == 0 {
+			table.Header = cells
+			continue
+		}
+		if len(cells) != len(table.Header) {
+			err = fmt.Errorf("table row %d has %d cells; header has %d", i, len(cells), len(table.Header))
+			goto end
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+end:
+	return table, err
+}
+
+func parseAlignedTable(lines []string) (table *Table) {
+	var starts []int
+
+	table = &Table{}
+	if strings.Contains(lines[0], "\t") {
+		table.Header = splitTabs(lines[0])
+		for _, line := range lines[1:] {
+			table.Rows = append(table.Rows, padCells(splitTabs(line), len(table.Header)))
+		}
+		goto end
+	}
+	for _, loc := range tableHeaderName.FindAllStringIndex(lines[0], -1) {
+		starts = append(starts, utf8.RuneCountInString(lines[0][:loc[0]]))
+	}
+	table.Header = splitAtColumns([]rune(lines[0]), starts)
+	for _, line := range lines[1:] {
+		table.Rows = append(table.Rows, splitAtColumns([]rune(line), starts))
+	}
+end:
+	return table
+}
+
+// splitTabs splits a line of tab separated cells, keeping empty cells so
+// later values stay in their columns
+func splitTabs(line string) (cells []string) {
+	cells = strings.Split(line, "\t")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func padCells(cells []string, n int) []string {
+	for len(cells) < n {
+		cells = append(cells, "")
+	}
+	return cells
+}
+
+// splitAtColumns splits line into one trimmed cell per column start. A word
+// that runs across a column start belongs to the earlier cell.
+func splitAtColumns(line []rune, starts []int) (cells []string) {
+	var pos int
+
+	cells = make([]string, len(starts))
+	for i, start := range starts {
+		from := min(max(start, pos), len(line))
+		end := len(line)
+		if i+1 < len(starts) {
+			end = max(min(starts[i+1], len(line)), from)
+		}
+		for end < len(line) && end > from && line[end-1] != ' ' && line[end] != ' ' {
+			end++
+		}
+		cells[i] = strings.TrimSpace(string(line[from:end]))
+		pos = end
+	}
+	return cells
+}
+
+// Column returns the index of the named column, matching case-insensitively
+// if there is no exact match
+func (tb *Table) Column(name string) (index int, ok bool) {
+	for i, h := range tb.Header {
+		if h == name {
+			return i, true
+		}
+	}
+	for i, h := range tb.Header {
+		if strings.EqualFold(h, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Values returns every row's value in the named column
+func (tb *Table) Values(column string) (values []string) {
+	i, ok := tb.Column(column)
+	if !ok {
+		return nil
+	}
+	for _, row := range tb.Rows {
+		values = append(values, row[i])
+	}
+	return values
+}
+
+// Row returns the row at index i
+func (tb *Table) Row(i int) TableRow {
+	return TableRow{table: tb, Cells: tb.Rows[i]}
+}
+
+// Where returns the rows whose value in column equals value
+func (tb *Table) Where(column, value string) (rows []TableRow) {
+	i, ok := tb.Column(column)
+	if !ok {
+		return nil
+	}
+	for _, cells := range tb.Rows {
+		if cells[i] == value {
+			rows = append(rows, TableRow{table: tb, Cells: cells})
+		}
+	}
+	return rows
+}
+
+// Find returns the first row whose value in column equals value
+func (tb *Table) Find(column, value string) (row TableRow, ok bool) {
+	rows := tb.Where(column, value)
+	if len(rows) == 0 {
+		return row, false
+	}
+	return rows[0], true
+}
+
+// String returns the table as tab separated lines
+func (tb *Table) String() string {
+	var sb strings.Builder
+	sb.WriteString(strings.Join(tb.Header, "\t") + "\n")
+	for _, row := range tb.Rows {
+		sb.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	return sb.String()
+}
+
+// Get returns the row's value in the named column, or "" if there is no such
+// column
+func (r TableRow) Get(column string) string {
+	i, ok := r.table.Column(column)
+	if !ok {
+		return ""
+	}
+	return r.Cells[i]
+}
+
+// ExpectRowCount fails the test unless the table has n rows
+func (tb *Table) ExpectRowCount(t *testing.T, n int) *Table {
+	t.Helper()
+	if len(tb.Rows) != n {
+		t.Errorf("Expected %d table rows, got %d\n%s", n, len(tb.Rows), tb)
+	}
+	return tb
+}
+
+// ExpectCell fails the test unless the row where column whereCol equals
+// whereValue has want in column col, e.g.:
+//
+//	table.ExpectCell(t, "NAME", "foo", "STATUS", "ok")
+func (tb *Table) ExpectCell(t *testing.T, whereCol, whereValue, col, want string) *Table {
+	t.Helper()
+	if _, ok := tb.Column(col); !ok {
+		t.Errorf("Expected table to have a %s column, got %q", col, tb.Header)
+		return tb
+	}
+	row, ok := tb.Find(whereCol, whereValue)
+	switch {
+	case !ok:
+		t.Errorf("Expected a table row where %s=%s\n%s", whereCol, whereValue, tb)
+	case row.Get(col) != want:
+		t.Errorf("Expected row where %s=%s to have %s=%q, got %q", whereCol, whereValue, col, want, row.Get(col))
+	}
+	return tb
+}
+
+// Table parses stdout with ParseTable, failing the test if it has no table
+func (r *CLIResult) Table() *Table {
+	r.t.Helper()
+	table, err := ParseTable(r.Stdout())
+	if err != nil {
+		r.t.Fatalf("Failed to parse table from stdout: %v%s", err, r.transcript())
+	}
+	return table
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestStripANSI(t *testing.T) {
+	got := testutil.StripANSI("\x1b[1;31mFAILED\x1b[0m \x1b]8;;http://x\x07link\x1b]8;;\x07\x1b[2K done")
+	if got != "FAILED link done" {
+		t.Errorf("Expected %q, got %q", "FAILED link done", got)
+	}
+}
+
+func TestBufferedWriter_PlainOutput(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	w.Printf("\x1b[32mok\x1b[0m first\n\x1b[1msecond\x1b[22m\n")
+	w.Errorf("\x1b[31merror\x1b[0m\n")
+
+	if w.ContainsStdout("ok first") {
+		t.Error("Expected raw stdout to contain escape codes")
+	}
+	if !w.ContainsPlainStdout("ok first") {
+		t.Errorf("Expected plain stdout to contain 'ok first', got %q", w.GetPlainStdout())
+	}
+	lines := w.GetPlainStdoutLines()
+	if len(lines) != 2 || lines[1] != "second" {
+		t.Errorf("Expected plain lines [ok first second], got %q", lines)
+	}
+	if w.GetPlainStderr() != "error\n" {
+		t.Errorf("Expected plain stderr %q, got %q", "error\n", w.GetPlainStderr())
+	}
+}
+
+func TestParseStyled(t *testing.T) {
+	st := testutil.ParseStyled("plain \x1b[1;31mFAILED\x1b[0m \x1b[38;5;208mindexed\x1b[39m \x1b[48;2;1;2;3mrgb\x1b[m")
+
+	if st.Plain() != "plain FAILED indexed rgb" {
+		t.Errorf("Expected plain text %q, got %q", "plain FAILED indexed rgb", st.Plain())
+	}
+	if !st.Has("FAILED", func(s testutil.Style) bool {
+		return s.Foreground == testutil.ColorRed && s.Bold
+	}) {
+		t.Errorf("Expected FAILED to be bold red, got %+v", st)
+	}
+	if st.Has("plain FAILED", func(s testutil.Style) bool { return s.Bold }) {
+		t.Error("Expected 'plain FAILED' to not be entirely bold")
+	}
+	style, ok := st.StyleOf("indexed")
+	if !ok || style.Foreground.String() != "color(208)" {
+		t.Errorf("Expected indexed to be color(208), got %v", style.Foreground)
+	}
+	style, _ = st.StyleOf("rgb")
+	if style.Background.String() != "#010203" || style.Foreground != (testutil.Color{}) {
+		t.Errorf("Expected rgb to have background #010203 and default foreground, got %+v", style)
+	}
+}
+
+func TestBufferedWriter_StyledStdout(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	w.Printf("Result: \x1b[92mPASSED\x1b[0m\n")
+
+	style, ok := w.StyledStdout().StyleOf("PASSED")
+	if !ok || style.Foreground != testutil.ColorBrightGreen {
+		t.Errorf("Expected PASSED in bright green, got %v", style.Foreground)
+	}
+}
+
+func TestBufferedWriter_PlainLinesDropEscapeOnlyLines(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	w.Printf("\x1b[2K\r\x1b[1A\nfirst\n\x1b[0m\nsecond\n\x1b[?25h\n")
+	w.Errorf("\x1b[31m\x1b[0m\nerror\n")
+
+	lines := w.GetPlainStdoutLines()
+	if len(lines) != 2 || lines[0] != "first" || lines[1] != "second" {
+		t.Errorf("Expected plain lines [first second], got %q", lines)
+	}
+	lines = w.GetPlainStderrLines()
+	if len(lines) != 1 || lines[0] != "error" {
+		t.Errorf("Expected plain stderr lines [error], got %q", lines)
+	}
+}
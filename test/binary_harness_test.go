@@ -0,0 +1,103 @@
+package test
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestBuildBinary_Cached(t *testing.T) {
+	first := testutil.BuildBinary(t, "./testdata/exitcmd")
+	second := testutil.BuildBinary(t, "./testdata/exitcmd")
+	if first != second {
+		t.Errorf("Expected cached binary %s, got %s", first, second)
+	}
+}
+
+func TestRunBinary_ExitCodeAndOutput(t *testing.T) {
+	bin := testutil.BuildBinary(t, "./testdata/exitcmd")
+
+	testutil.RunBinaryWithArgs(t, bin, &testutil.ExecArgs{
+		Args:  []string{"3"},
+		Env:   map[string]string{"EXITCMD_MODE": "test"},
+		Stdin: strings.NewReader("hello"),
+	}).
+		ExpectExitCode(3).
+		ExpectError().
+		ExpectStdout("stdin: hello\n").
+		ExpectStderrContains("mode: test")
+
+	testutil.RunBinary(t, bin).ExpectSuccess()
+}
+
+func TestStartBinary_Signal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("os.Interrupt cannot be sent to processes on Windows")
+	}
+	bin := testutil.BuildBinary(t, "./testdata/exitcmd")
+
+	proc := testutil.StartBinary(t, bin, &testutil.ExecArgs{Args: []string{"wait"}})
+	deadline := time.Now().Add(10 * time.Second)
+	for !proc.Output().ContainsStdout("waiting") {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for process to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := proc.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	proc.Wait().ExpectExitCode(130).ExpectStdoutContains("interrupted")
+}
+
+func TestStartBinary_KilledAtCleanup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exitcmd waits for os.Interrupt which Windows cannot send")
+	}
+	bin := testutil.BuildBinary(t, "./testdata/exitcmd")
+
+	var proc *testutil.BinaryProcess
+	t.Run("start", func(t *testing.T) {
+		proc = testutil.StartBinary(t, bin, &testutil.ExecArgs{Args: []string{"wait"}})
+	})
+	if proc.Cmd.ProcessState == nil {
+		t.Fatal("Expected process to be reaped when the subtest ended")
+	}
+	if got := proc.Wait().ExitCode; got != -1 {
+		t.Errorf("Expected killed process to have exit code -1, got %d", got)
+	}
+}
+
+func TestRunBinary_ScriptedInput(t *testing.T) {
+	bin := testutil.BuildBinary(t, "./testdata/exitcmd")
+	w := testutil.NewBufferedWriter()
+	in := testutil.NewScriptedInput(t, w).Expect(`Name\? $`, "Ann\n")
+
+	start := time.Now()
+	testutil.RunBinaryWithArgs(t, bin, &testutil.ExecArgs{
+		Args:   []string{"prompt"},
+		Stdin:  in,
+		Writer: w,
+	}).ExpectSuccess().ExpectStdout("Name? Hi Ann\n")
+	if elapsed := time.Since(start); elapsed > testutil.DefaultPromptTimeout {
+		t.Errorf("Expected the process to be reaped without waiting for the input to time out, took %s", elapsed)
+	}
+}
+
+func TestRunBinaryWithArgs_ReusedArgs(t *testing.T) {
+	bin := testutil.BuildBinary(t, "./testdata/exitcmd")
+	args := &testutil.ExecArgs{Stdin: strings.NewReader("")}
+
+	first := testutil.RunBinaryWithArgs(t, bin, args)
+	second := testutil.RunBinaryWithArgs(t, bin, args)
+	if args.Writer != nil || args.Context != nil {
+		t.Errorf("Expected args to be left unchanged, got Writer=%v Context=%v", args.Writer, args.Context)
+	}
+	if first.Writer == second.Writer {
+		t.Error("Expected each run to capture output in its own writer")
+	}
+}
@@ -192,3 +192,68 @@ func TestBufferedWriter_ConcurrentAccess(t *testing.T) {
 		t.Error("Expected some doterr lines from concurrent writes")
 	}
 }
+
+func TestBufferedWriter_VerbosityRange(t *testing.T) {
+	writer := testutil.NewTestBufferedWriter(t)
+	writer.SetVerbosityRange(0, 5)
+	writer.SetVerbosity(0)
+
+	writer.V(0).Printf("always\n")
+	writer.Printf("normal\n")
+	trace := writer.V(5)
+	trace.Printf("trace 1\n")
+	if writer.GetStdout() != "always\n" {
+		t.Errorf("Expected only level 0 output at verbosity 0, got %q", writer.GetStdout())
+	}
+
+	// Children share the writer's settings, so later changes apply to them
+	writer.SetVerbosity(5)
+	trace.Printf("trace 2\n")
+	if !writer.ContainsStdout("trace 2") {
+		t.Errorf("Expected level 5 output at verbosity 5, got %q", writer.GetStdout())
+	}
+	if writer.V(5) != trace {
+		t.Error("Expected V(5) to return the same writer each time")
+	}
+	if writer.V(3) != writer.V3() {
+		t.Error("Expected V3() to return V(3)")
+	}
+}
+
+func TestBufferedWriter_QuietAppliesToEveryLevel(t *testing.T) {
+	writer := testutil.NewBufferedWriter()
+	v2 := writer.V2()
+	writer.SetQuiet(true)
+
+	v2.Printf("hidden\n")
+	writer.Loud().Printf("loud\n")
+	if writer.GetStdout() != "loud\n" {
+		t.Errorf("Expected only loud output once quiet, got %q", writer.GetStdout())
+	}
+}
+
+func TestBufferedWriter_OutOfRangePanicsWithoutTest(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected SetVerbosity(4) to panic for a writer without a test")
+		}
+	}()
+	testutil.NewBufferedWriter().SetVerbosity(4)
+}
+
+func TestNewTestBufferedWriter_OutOfRangeFails(t *testing.T) {
+	expectFailure(t, "TestNewTestBufferedWriter_OutOfRangeFailing",
+		"Invalid verbosity for BufferedWriter; must be between 1-3; got 4",
+		"Invalid verbosity range for BufferedWriter; 3 is greater than 1",
+		"verbosity still 2")
+}
+
+func TestNewTestBufferedWriter_OutOfRangeFailing(t *testing.T) {
+	failingTest(t)
+	w := testutil.NewTestBufferedWriter(t)
+	w.SetVerbosity(2)
+	w.SetVerbosity(4)
+	w.SetVerbosityRange(3, 1)
+	// The test keeps running after the failures, with the settings unchanged
+	t.Logf("verbosity still %d", w.Verbosity())
+}
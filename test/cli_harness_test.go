@@ -0,0 +1,108 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestRunCLI_Success(t *testing.T) {
+	result := testutil.RunCLI(t, newGreetCmd(), "--name", "Alice", "--shout").
+		ExpectSuccess().
+		ExpectStdout("HELLO, ALICE!\n").
+		ExpectStderrEmpty().
+		ExpectLogContains("name=Alice")
+
+	if len(result.Logs()) != 1 {
+		t.Errorf("Expected 1 log entry, got %d", len(result.Logs()))
+	}
+}
+
+func TestRunCLI_Error(t *testing.T) {
+	result := testutil.RunCLI(t, newGreetCmd(), "--name", "nobody").
+		ExpectExitCode(cliutil.ExitKnownRuntimeError).
+		ExpectErrorIs(errNobody).
+		ExpectStderrContains("nobody to greet")
+
+	timeline := result.Timeline().String()
+	expected := "log: ERROR: greeting failed [err=nobody to greet]\nstderr: Error: nobody to greet\n"
+	if timeline != expected {
+		t.Errorf("Expected timeline %q, got %q", expected, timeline)
+	}
+}
+
+func TestRunCLI_FlagParseError(t *testing.T) {
+	testutil.RunCLI(t, newGreetCmd(), "--unknown=1").
+		ExpectExitCode(cliutil.ExitOptionsParseError).
+		ExpectError()
+}
+
+func TestRunCLIWithArgs_EnvStdinAndDir(t *testing.T) {
+	dir := dt.DirPath(t.TempDir())
+
+	testutil.RunCLIWithArgs(t, newGreetCmd(), &testutil.CLIRunArgs{
+		Args: []string{"--name=Bob"},
+		Env:  map[string]string{"GREETING": "Howdy"},
+		Dir:  dir,
+	}).ExpectSuccess().ExpectStdout("Howdy, Bob!\n")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(wd, string(dir.Base())) {
+		t.Errorf("Expected working directory %s, got %s", dir, wd)
+	}
+
+	testutil.RunCLIWithArgs(t, newCatCmd(), &testutil.CLIRunArgs{
+		Stdin: strings.NewReader("line 1\nline 2\n"),
+	}).ExpectSuccess().ExpectStdout("line 1\nline 2\n")
+}
+
+func TestRunCLI_RestoresPackageWriter(t *testing.T) {
+	prev := cliutil.GetWriter()
+	args := &testutil.CLIRunArgs{Args: []string{"--name", "Ann"}}
+	result := testutil.RunCLIWithArgs(t, newGreetCmd(), args)
+	if got := cliutil.GetWriter(); got != prev {
+		t.Errorf("Expected cliutil writer to be restored to %v, got %v", prev, got)
+	}
+	if cliutil.GetWriter() == cliutil.Writer(result.Writer) {
+		t.Error("Expected the test's BufferedWriter not to stay installed")
+	}
+	if args.Context != nil {
+		t.Error("Expected RunCLIWithArgs not to modify the caller's args")
+	}
+}
+
+func TestRunCLIWithArgs_VerbosityZero(t *testing.T) {
+	w := testutil.NewTestBufferedWriter(t)
+	w.SetVerbosityRange(0, 3)
+	result := testutil.RunCLIWithArgs(t, newGreetCmd(), &testutil.CLIRunArgs{
+		Args:         []string{"--name", "Ann"},
+		Writer:       w,
+		SetVerbosity: true,
+		Verbosity:    0,
+	})
+	if got := result.Writer.Verbosity(); got != 0 {
+		t.Errorf("Expected verbosity 0, got %d", got)
+	}
+	result = testutil.RunCLI(t, newGreetCmd(), "--name", "Ann")
+	if got := result.Writer.Verbosity(); got != 3 {
+		t.Errorf("Expected default verbosity 3 without SetVerbosity, got %d", got)
+	}
+}
+
+func TestRunCLI_NegativeNumberArgs(t *testing.T) {
+	testutil.RunCLI(t, newGreetCmd(), "--name", "x", "-5", "-1.5").
+		ExpectSuccess().
+		ExpectStdout("Hello, x!\n")
+	result := testutil.RunCLI(t, newGreetCmd(), "-5", "-x").
+		ExpectExitCode(cliutil.ExitOptionsParseError)
+	if result.Err == nil || result.Err.Error() != "unknown flag(s): -x" {
+		t.Errorf("Expected only -x to be reported as unknown, got %v", result.Err)
+	}
+}
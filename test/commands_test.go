@@ -0,0 +1,144 @@
+package test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mikeschinkel/go-cliutil"
+)
+
+// Commands used to exercise the CLI harnesses
+
+var errNobody = errors.New("nobody to greet")
+
+type greetCmd struct {
+	*cliutil.CmdBase
+	name  string
+	shout bool
+}
+
+func newGreetCmd() cliutil.Command {
+	c := &greetCmd{}
+	c.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "greet",
+		Usage:       "greet [--name=<name>] [--shout]",
+		Description: "Greets someone",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "greet",
+			FlagDefs: []cliutil.FlagDef{
+				{Name: "name", String: &c.name, Default: "World", Usage: "Name to greet"},
+				{Name: "shout", Bool: &c.shout, Usage: "Greet loudly"},
+			},
+		}},
+	})
+	return c
+}
+
+func (c *greetCmd) Handle() error {
+	greeting := os.Getenv("GREETING")
+	if greeting == "" {
+		greeting = "Hello"
+	}
+	if c.name == "nobody" {
+		c.Logger.Error("greeting failed", "err", errNobody)
+		c.Writer.Errorf("Error: %v\n", errNobody)
+		return errNobody
+	}
+	msg := greeting + ", " + c.name + "!"
+	if c.shout {
+		msg = strings.ToUpper(msg)
+	}
+	c.Logger.Info("greeting", "name", c.name)
+	c.Writer.Printf("%s\n", msg)
+	return nil
+}
+
+type catCmd struct {
+	*cliutil.CmdBase
+}
+
+func newCatCmd() cliutil.Command {
+	return &catCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "cat",
+			Usage:       "cat",
+			Description: "Copies stdin to stdout",
+		}),
+	}
+}
+
+func (c *catCmd) Handle() error {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	c.Writer.Printf("%s", data)
+	return nil
+}
+
+type confirmCmd struct {
+	*cliutil.CmdBase
+}
+
+func newConfirmCmd() cliutil.Command {
+	return &confirmCmd{
+		CmdBase: cliutil.NewCmdBase(cliutil.CmdArgs{
+			Name:        "confirm",
+			Usage:       "confirm",
+			Description: "Prompts for confirmation and a name",
+		}),
+	}
+}
+
+func (c *confirmCmd) Handle() error {
+	in := bufio.NewReader(os.Stdin)
+	c.Writer.Printf("Delete everything? [y/N] ")
+	answer, err := in.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(answer) != "y" {
+		c.Writer.Printf("Aborted\n")
+		return nil
+	}
+	c.Writer.Printf("Your name: ")
+	name, err := in.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	c.Writer.Printf("Deleted by %s\n", strings.TrimSpace(name))
+	return nil
+}
+
+type listCmd struct {
+	*cliutil.CmdBase
+	warn bool
+}
+
+func newListCmd() cliutil.Command {
+	c := &listCmd{}
+	c.CmdBase = cliutil.NewCmdBase(cliutil.CmdArgs{
+		Name:        "list",
+		Usage:       "list [--warn]",
+		Description: "Lists items as JSON",
+		FlagSets: []*cliutil.FlagSet{{
+			Name: "list",
+			FlagDefs: []cliutil.FlagDef{
+				{Name: "warn", Bool: &c.warn, Usage: "Print a warning to stdout"},
+			},
+		}},
+	})
+	return c
+}
+
+func (c *listCmd) Handle() error {
+	if c.warn {
+		c.Writer.Printf("Warning: cache is stale\n")
+	}
+	c.Writer.Printf(`{"count":3,"generated":"2025-01-01T00:00:00Z","items":[` +
+		`{"id":101,"name":"a"},{"id":102,"name":"b"},{"id":103,"name":"x"}]}` + "\n")
+	return nil
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// failingEnv is set when a test binary is re-run by runChildTest so the tests
+// that only run in a child binary run instead of skipping
+const failingEnv = "TESTUTIL_RUN_FAILING"
+
+// failingTest skips t unless it is being run by expectFailure. Tests that
+// exercise assertion failures call it first since their failures are real.
+func failingTest(t *testing.T) {
+	childOnly(t)
+}
+
+// childOnly skips t unless it is being run in a child test binary by
+// runChildTest, for tests whose output is checked by the test that runs them
+func childOnly(t *testing.T) {
+	if os.Getenv(failingEnv) == "" {
+		t.Skip("only run in a child test binary")
+	}
+}
+
+// expectFailure re-runs the named failing test in a child test binary and
+// fails t unless that test failed with output containing each of want
+func expectFailure(t *testing.T, name string, want ...string) {
+	t.Helper()
+	out, err := runChildTest(name)
+	if err == nil {
+		t.Fatalf("Expected %s to fail, it passed:\n%s", name, out)
+	}
+	if !strings.Contains(out, "--- FAIL: "+name) {
+		t.Fatalf("Expected %s to fail, got:\n%s", name, out)
+	}
+	for _, s := range want {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected failure output of %s to contain %q, got:\n%s", name, s, out)
+		}
+	}
+}
+
+// runChildTest re-runs the named test in a child test binary with -test.v and
+// returns its combined output
+func runChildTest(name string) (out string, err error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$", "-test.v")
+	cmd.Env = append(os.Environ(), failingEnv+"=1")
+	b, err := cmd.CombinedOutput()
+	return string(b), err
+}
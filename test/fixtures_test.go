@@ -0,0 +1,77 @@
+package test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+type fixtureConfig struct {
+	Name string   `json:"name"`
+	Port int      `json:"port"`
+	Tags []string `json:"tags"`
+}
+
+func TestLoadJSON(t *testing.T) {
+	cfg := testutil.LoadJSON[fixtureConfig](t, "fixtures/config.json", nil)
+	if cfg.Name != "app" || cfg.Port != 8080 || !slices.Equal(cfg.Tags, []string{"a", "b"}) {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	m := testutil.LoadJSON[map[string]any](t, "fixtures/config.json", nil)
+	if m["name"] != "app" {
+		t.Errorf("Expected name=app, got %v", m["name"])
+	}
+}
+
+func TestLoadLines(t *testing.T) {
+	lines := testutil.LoadLines(t, "fixtures/names.txt", nil)
+	if want := []string{"alpha", "beta", "gamma"}; !slices.Equal(lines, want) {
+		t.Errorf("Expected %q, got %q", want, lines)
+	}
+}
+
+func TestLoadTxtar(t *testing.T) {
+	a := testutil.LoadTxtar(t, "fixtures/bundle.txtar", nil)
+	f, ok := a.File("b/c.txt")
+	if !ok || string(f.Data) != "C\n" {
+		t.Errorf("Expected b/c.txt to hold C, got %q (found=%v)", f.Data, ok)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	got := testutil.LoadTemplate(t, "fixtures/greeting.tmpl", map[string]string{
+		"Home": "/home/dev",
+		"User": "dev",
+	}, nil)
+	if want := "home=/home/dev user=dev\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestLoadFixture_Optional(t *testing.T) {
+	args := &testutil.FixtureArgs{Optional: true}
+	if data := testutil.LoadFixture(t, "fixtures/missing.txt", args); data != nil {
+		t.Errorf("Expected nil for missing fixture, got %q", data)
+	}
+	if cfg := testutil.LoadJSON[fixtureConfig](t, "fixtures/missing.json", args); cfg.Name != "" {
+		t.Errorf("Expected zero config for missing fixture, got %+v", cfg)
+	}
+	if lines := testutil.LoadLines(t, "fixtures/missing.txt", args); lines != nil {
+		t.Errorf("Expected no lines for missing fixture, got %q", lines)
+	}
+	if a := testutil.LoadTxtar(t, "fixtures/missing.txtar", args); len(a.Files) != 0 {
+		t.Errorf("Expected empty archive for missing fixture, got %d files", len(a.Files))
+	}
+}
+
+func TestFixturePath_IgnoresWorkingDirectory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	lines := testutil.LoadLines(t, "fixtures/names.txt", nil)
+	if len(lines) != 3 {
+		t.Errorf("Expected fixture to load from the package testdata, got %q", lines)
+	}
+	if got := testutil.FixturePath("/abs/file.txt"); got != "/abs/file.txt" {
+		t.Errorf("Expected absolute path unchanged, got %s", got)
+	}
+}
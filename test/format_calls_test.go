@@ -0,0 +1,71 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestBufferedWriter_ReportedErrors(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	pathErr := &fs.PathError{Op: "open", Path: "config.json", Err: fs.ErrNotExist}
+	err := fmt.Errorf("loading config:\n%w", pathErr)
+	w.Errorf("Error: %v (attempt %d)\n", err, 2)
+
+	if w.GetStderr() != "Error: loading config:; open config.json: file does not exist (attempt 2)\n" {
+		t.Errorf("Expected flattened stderr text, got %q", w.GetStderr())
+	}
+	if !w.ReportedErrorIs(fs.ErrNotExist) {
+		t.Error("Expected fs.ErrNotExist to be reported")
+	}
+	var target *fs.PathError
+	if !w.ReportedErrorAs(&target) || target.Path != "config.json" {
+		t.Errorf("Expected a *fs.PathError for config.json, got %v", target)
+	}
+
+	calls := w.ErrorfCalls()
+	if len(calls) != 1 || calls[0].Format != "Error: %v (attempt %d)\n" || calls[0].Args[1] != 2 {
+		t.Errorf("Expected the Errorf call with its format and args, got %+v", calls)
+	}
+}
+
+func TestCLIResult_ExpectReportedErrorIs(t *testing.T) {
+	testutil.RunCLI(t, newGreetCmd(), "--name", "nobody").
+		ExpectError().
+		ExpectReportedErrorIs(errNobody)
+
+	w := testutil.NewBufferedWriter()
+	w.Errorf("Error: %s\n", errors.New("plain"))
+	if w.ReportedErrorIs(errNobody) {
+		t.Error("Expected an unrelated error to not match")
+	}
+}
+
+func TestBufferedWriter_PrintfCalls(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	w.SetVerbosity(1)
+	w.Printf("Scanning %s\n", "/tmp/x")
+	w.Printf("Processed %d files\n", uint(12))
+	w.V2().Printf("Skipped %d files\n", 3)
+	w.Errorf("Warning: %s\n", "slow disk")
+
+	call := w.ExpectFormat(t, "Processed %d files\n")
+	if n, ok := call.IntArg(0); !ok || n < 10 {
+		t.Errorf("Expected argument 0 to be at least 10, got %v", call.Arg(0))
+	}
+	if w.PrintedFormat("Skipped %d files\n") {
+		t.Error("Expected a Printf suppressed by verbosity to not be recorded")
+	}
+	if got := len(w.PrintfCalls()); got != 2 {
+		t.Errorf("Expected 2 Printf calls, got %d", got)
+	}
+	if got := len(w.ErrorfCalls()); got != 1 {
+		t.Errorf("Expected 1 Errorf call, got %d", got)
+	}
+	if calls := w.FormatCalls(); len(calls) != 3 || calls[2].Stream != testutil.StderrStream {
+		t.Errorf("Expected 3 calls ending with Errorf, got %+v", calls)
+	}
+}
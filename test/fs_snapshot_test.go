@@ -0,0 +1,59 @@
+package test
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestFSSnapshot_Diff(t *testing.T) {
+	root := testutil.MakeTree(t, testutil.Tree{
+		"src/main.go":  testutil.TreeFile("package main\n\nfunc main() {}\n"),
+		"src/old.go":   testutil.TreeFile("package main\n"),
+		"run.sh":       testutil.TreeFile("echo hi\n"),
+		"out/":         testutil.TreeDir(),
+		"latest":       testutil.TreeSymlink("out"),
+		"README.md":    testutil.TreeFile("# App\n"),
+		"out/keep.txt": testutil.TreeFile("keep\n"),
+	})
+	changes := testutil.WatchDir(t, root)
+
+	dir := string(root)
+	_ = os.WriteFile(dir+"/out/app", []byte("binary\x00data"), 0o755)
+	_ = os.WriteFile(dir+"/src/main.go", []byte("package main\n\nfunc main() { run() }\n"), 0o644)
+	_ = os.Remove(dir + "/src/old.go")
+	_ = os.Chmod(dir+"/run.sh", 0o755)
+
+	cc := changes()
+	want := []string{"out/app", "run.sh", "src/main.go", "src/old.go"}
+	if !slices.Equal(cc.Paths(), want) {
+		t.Fatalf("Expected changes to %q, got:\n%s", want, cc)
+	}
+	cc.Expect(t, map[string]testutil.FSChangeKind{
+		"out/app":     testutil.FSAdded,
+		"src/old.go":  testutil.FSRemoved,
+		"src/main.go": testutil.FSModified,
+		"run.sh":      testutil.FSModified,
+	})
+	if !cc[1].ModeChanged() || cc[1].ContentChanged() {
+		t.Errorf("Expected run.sh to change mode only, got %s", cc[1])
+	}
+	if !strings.Contains(cc.String(), "    -func main() {}\n    +func main() { run() }\n") {
+		t.Errorf("Expected a content diff of src/main.go, got:\n%s", cc)
+	}
+	cc[:1].ExpectOnlyUnder(t, "out")
+}
+
+func TestFSSnapshot_Golden(t *testing.T) {
+	root := testutil.MakeTree(t, testutil.Tree{
+		"a.txt":     testutil.TreeFile("hello\nworld\n"),
+		"bin/tool":  testutil.TreeFile("#!/bin/sh\n").WithMode(0o755),
+		"data.bin":  testutil.TreeFile("\x00\x01"),
+		"link":      testutil.TreeSymlink("a.txt"),
+		"notes.txt": testutil.TreeFile("no newline"),
+	})
+	testutil.SnapshotDir(t, root).ExpectGolden(t, "testdata/golden/tree.txt")
+}
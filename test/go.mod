@@ -4,7 +4,7 @@ go 1.25.3
 
 require (
 	github.com/mikeschinkel/go-cliutil v0.3.0
-	github.com/mikeschinkel/go-dt v0.3.3 // indirect
+	github.com/mikeschinkel/go-dt v0.3.3
 	github.com/mikeschinkel/go-testutil v0.2.0
 )
 
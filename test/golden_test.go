@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+// update is the common per-package golden flag; testutil looks it up instead
+// of defining its own so the two do not clash
+var _ = flag.Bool("update", false, "update golden files")
+
+func TestCLIResult_ExpectGolden(t *testing.T) {
+	testutil.RunCLI(t, newGreetCmd(), "--name", "Alice Smith").
+		ExpectGolden("testdata/golden/greet.txtar", &testutil.GoldenArgs{Logs: true})
+}
+
+func TestCLIResult_ExpectGoldenInterleaved(t *testing.T) {
+	testutil.RunCLI(t, newGreetCmd(), "--name", "nobody").
+		ExpectGolden("testdata/golden/greet_error.txtar", &testutil.GoldenArgs{
+			Interleaved: true,
+			Logs:        true,
+		})
+}
+
+func TestLineDiff(t *testing.T) {
+	got := testutil.LineDiff("a\nb\nc\n", "a\nB\nc\nd\n")
+	want := " a\n-b\n+B\n c\n+d\n"
+	if got != want {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestCLIResult_GoldenTrailingNewline(t *testing.T) {
+	golden := func(input string) []byte {
+		return testutil.RunCLIWithArgs(t, newCatCmd(), &testutil.CLIRunArgs{
+			Stdin: strings.NewReader(input),
+		}).Golden(nil)
+	}
+	withNL, withoutNL := golden("Hello\n"), golden("Hello")
+	if bytes.Equal(withNL, withoutNL) {
+		t.Fatalf("Expected output with and without a final newline to differ, both were:\n%s", withNL)
+	}
+	if !bytes.Contains(withoutNL, []byte("-- stdout --\nHello\n\\ No newline at end\n")) {
+		t.Errorf("Expected missing newline to be marked, got:\n%s", withoutNL)
+	}
+}
+
+func TestCLIResult_GoldenEscapesMarkers(t *testing.T) {
+	got := testutil.RunCLIWithArgs(t, newCatCmd(), &testutil.CLIRunArgs{
+		Stdin: strings.NewReader("before\n-- stderr --\n\\ back\n"),
+	}).Golden(nil)
+	a := testutil.ParseTxtar(got)
+	if len(a.Files) != 4 {
+		t.Fatalf("Expected 4 sections, got %d:\n%s", len(a.Files), got)
+	}
+	stdout, _ := a.File("stdout")
+	if want := "before\n\\-- stderr --\n\\\\ back\n"; string(stdout.Data) != want {
+		t.Errorf("Expected escaped stdout %q, got %q", want, stdout.Data)
+	}
+}
+
+func TestCLIResult_GoldenEscapesError(t *testing.T) {
+	r := &testutil.CLIResult{
+		Err:        errors.New("bad input\n-- stdout --\nfaked"),
+		ExitCode:   1,
+		Writer:     testutil.NewBufferedWriter(),
+		LogHandler: testutil.NewBufferedLogHandler(),
+	}
+	a := testutil.ParseTxtar(r.Golden(nil))
+	if len(a.Files) != 5 {
+		t.Fatalf("Expected 5 sections, got %d", len(a.Files))
+	}
+	section, _ := a.File("error")
+	if want := "bad input\n\\-- stdout --\nfaked\n"; string(section.Data) != want {
+		t.Errorf("Expected escaped error %q, got %q", want, section.Data)
+	}
+}
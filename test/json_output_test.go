@@ -0,0 +1,100 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestCLIResult_JSON(t *testing.T) {
+	result := testutil.RunCLI(t, newListCmd()).
+		ExpectSuccess().
+		ExpectJSONPath("items[2].name", "x").
+		ExpectJSONPath("count", 3).
+		ExpectJSON(`{
+			"items": [{"name": "a", "id": 1}, {"name": "b", "id": 2}, {"name": "x", "id": 3}],
+			"count": 3.0
+		}`, "generated", "items[*].id")
+
+	id, err := result.JSON().Get("items[0].id")
+	if err != nil || fmt.Sprint(id) != "101" {
+		t.Errorf("Expected items[0].id to be 101, got %v, %v", id, err)
+	}
+}
+
+func TestParseJSON_LeakedText(t *testing.T) {
+	result := testutil.RunCLI(t, newListCmd(), "--warn")
+
+	_, err := testutil.ParseJSON([]byte(result.Stdout()))
+	if !errors.Is(err, testutil.ErrNotJSON) {
+		t.Fatalf("Expected ErrNotJSON, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"Warning: cache is stale"`) {
+		t.Errorf("Expected error to quote the leaked text, got %v", err)
+	}
+}
+
+func TestParseJSON_TrailingText(t *testing.T) {
+	for _, data := range []string{"{\"n\":1}]", "{\"n\":1}\n}\n", "[1] [2]", "{} garbage"} {
+		_, err := testutil.ParseJSON([]byte(data))
+		if !errors.Is(err, testutil.ErrNotJSON) || !strings.Contains(err.Error(), "unexpected text after JSON value") {
+			t.Errorf("Expected trailing text in %q to be an error, got %v", data, err)
+		}
+	}
+	_, err := testutil.ParseJSON([]byte("{\"n\":1}\n}\n"))
+	if err == nil || !strings.Contains(err.Error(), `at line 2: "}"`) {
+		t.Errorf("Expected the error to quote the trailing line, got %v", err)
+	}
+	_, err = testutil.ParseJSON([]byte(" {\"n\":1} \n\n"))
+	if err != nil {
+		t.Errorf("Expected trailing whitespace to be allowed, got %v", err)
+	}
+}
+
+func TestParseJSONLines(t *testing.T) {
+	values, err := testutil.ParseJSONLines([]byte("{\"n\":1}\n\n{\"n\":2}\n"))
+	if err != nil || len(values) != 2 {
+		t.Fatalf("Expected 2 values, got %d, %v", len(values), err)
+	}
+	_, err = testutil.ParseJSONLines([]byte("{\"n\":1}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	diffs, err := testutil.DiffJSON(
+		`{"a":1,"b":{"c":[1,2,3]},"extra":true}`,
+		map[string]any{"a": 2, "b": map[string]any{"c": []int{1, 2}}, "missing": "x"},
+	)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"a: got 1, want 2",
+		"b.c[2]: unexpected 3",
+		"extra: unexpected true",
+		`missing: missing, want "x"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected diffs:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestDiffJSON_Numbers(t *testing.T) {
+	diffs, err := testutil.DiffJSON(`{"id":9007199254740993,"n":1.0,"e":1e2}`,
+		`{"id":9007199254740992,"n":1,"e":100}`)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].String() != "id: got 9007199254740993, want 9007199254740992" {
+		t.Errorf("Expected only the large ids to differ, got %v", diffs)
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestBufferedLogHandler_GuardTest_NoLateCalls(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler().GuardTest(t)
+	logger := slog.New(handler)
+
+	logger.Info("During test")
+
+	if len(handler.LateLogCalls()) != 0 {
+		t.Errorf("Expected no late log calls, got %v", handler.LateLogCalls())
+	}
+}
+
+func TestBufferedLogHandler_GuardTest_LateCall(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler()
+	logger := slog.New(handler)
+
+	t.Run("owner", func(t *testing.T) {
+		handler.GuardTest(t)
+		logger.Info("During owner")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Warn("After owner finished")
+	}()
+	<-done
+
+	calls := handler.LateLogCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 late log call, got %d", len(calls))
+	}
+	call := calls[0]
+	if call.Test != "TestBufferedLogHandler_GuardTest_LateCall/owner" {
+		t.Errorf("Expected owner test name, got %q", call.Test)
+	}
+	if call.Message != "After owner finished" || call.Level != "WARN" {
+		t.Errorf("Unexpected late log call: %s", call)
+	}
+	if !strings.HasPrefix(call.Source, "late_log_guard_test.go:") {
+		t.Errorf("Expected source in late_log_guard_test.go, got %q", call.Source)
+	}
+
+	// Late records are still captured
+	if !handler.Contains("After owner finished") {
+		t.Error("Expected late record to be captured in the buffer")
+	}
+
+	// Taken so ReportLateLogs in TestMain does not fail the run
+	taken := testutil.TakeLateLogCalls()
+	if len(taken) != 1 || taken[0] != call {
+		t.Errorf("Expected the late call to be taken once, got %v", taken)
+	}
+	if len(testutil.TakeLateLogCalls()) != 0 {
+		t.Error("Expected taken late calls not to be returned again")
+	}
+}
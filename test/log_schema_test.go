@@ -0,0 +1,67 @@
+package test
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestLogSchema_ValidEntries(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler()
+	logger := slog.New(handler)
+
+	schema := &testutil.LogSchema{
+		KeyPattern: testutil.SnakeCaseKeys,
+		Attrs: map[string]testutil.LogAttrKind{
+			"err":         testutil.ErrorAttr,
+			"duration_ms": testutil.IntAttr,
+		},
+		Required: map[string][]string{
+			"request completed": {"request_id", "duration_ms"},
+		},
+	}
+
+	logger.Info("request completed", "request_id", "abc", "duration_ms", 42)
+	logger.Error("request failed", "err", errors.New("boom"))
+	logger.Info("nested", slog.Group("http", slog.Int("duration_ms", 7)))
+
+	handler.AssertSchema(t, schema)
+}
+
+func TestLogSchema_Violations(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler()
+	logger := slog.New(handler)
+
+	schema := testutil.LoadLogSchema(t, "testdata/log_schema.json")
+
+	logger.Info("request completed", "requestID", "abc")
+	logger.Error("request failed", "err", "boom")
+	logger.Info("slow", slog.Group("http", slog.Float64("duration_ms", 7.5)))
+
+	violations := handler.ValidateSchema(schema)
+	expected := []string{
+		`log entry 1 "request completed": attr "requestID": key does not match`,
+		`log entry 1 "request completed": attr "request_id": required attribute is missing`,
+		`log entry 1 "request completed": attr "duration_ms": required attribute is missing`,
+		`log entry 2 "request failed": attr "err": expected error value, got string`,
+		`log entry 3 "slow": attr "http.duration_ms": expected int value, got float64`,
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected %d violations, got %d: %v", len(expected), len(violations), violations)
+	}
+	for i, v := range violations {
+		if !strings.HasPrefix(v.String(), expected[i]) {
+			t.Errorf("Expected violation %d to start with %q, got %q", i, expected[i], v.String())
+		}
+	}
+}
+
+func TestParseLogSchema_InvalidKind(t *testing.T) {
+	_, err := testutil.ParseLogSchema([]byte(`{"attrs": {"count": "integer"}}`))
+	if err == nil {
+		t.Error("Expected error for unknown attribute kind")
+	}
+}
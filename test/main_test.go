@@ -0,0 +1,18 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestMain(m *testing.M) {
+	code := testutil.ReportLateLogs(m.Run())
+	err := testutil.RemoveBuiltBinaries()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove test binaries: %v\n", err)
+	}
+	os.Exit(code)
+}
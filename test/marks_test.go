@@ -0,0 +1,39 @@
+package test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestMarks(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	h := testutil.NewBufferedLogHandler()
+	logger := slog.New(h)
+
+	w.Printf("step 1\n")
+	logger.Info("first")
+
+	m := w.Mark()
+	w.Printf("step 2\n")
+	w.Errorf("warning 2\n")
+	logger.Info("second")
+
+	if got := w.StdoutSince(m); got != "step 2\n" {
+		t.Errorf("Expected stdout since mark %q, got %q", "step 2\n", got)
+	}
+	if got := w.StderrSince(m); got != "warning 2\n" {
+		t.Errorf("Expected stderr since mark %q, got %q", "warning 2\n", got)
+	}
+	entries := h.EntriesSince(m)
+	if len(entries) != 1 || entries[0].Message != "second" {
+		t.Errorf("Expected only the second log entry since mark, got %v", entries)
+	}
+	if w.GetStdout() != "step 1\nstep 2\n" {
+		t.Errorf("Expected full stdout to be kept, got %q", w.GetStdout())
+	}
+	if got := h.EntriesSince(h.Mark()); len(got) != 0 {
+		t.Errorf("Expected nothing since a fresh mark, got %v", got)
+	}
+}
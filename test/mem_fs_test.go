@@ -0,0 +1,201 @@
+package test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func newTestMemFS(t *testing.T) *testutil.MemFS {
+	return testutil.NewTestMemFS(t, testutil.Tree{
+		"data/app.db":    testutil.TreeFile("db"),
+		"data/notes.txt": testutil.TreeFile("notes\n"),
+		"logs/":          testutil.TreeDir(),
+		"run.sh":         testutil.TreeFile("echo hi\n").WithMode(0o755),
+	})
+}
+
+func TestMemFS_ImplementsFS(t *testing.T) {
+	mfs := newTestMemFS(t)
+	err := fstest.TestFS(mfs, "data/app.db", "data/notes.txt", "logs", "run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := mfs.Stat("run.sh")
+	if err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("Expected run.sh mode 0755, got %v (err=%v)", info, err)
+	}
+}
+
+func TestMemFS_WriteOperations(t *testing.T) {
+	mfs := newTestMemFS(t)
+	if err := mfs.MkdirAll("out/a/b", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.WriteFile("out/a/b/c.txt", []byte("c"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.Rename("out/a", "out/z"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := mfs.ReadFile("out/z/b/c.txt")
+	if err != nil || string(data) != "c" {
+		t.Errorf("Expected renamed file to hold c, got %q (err=%v)", data, err)
+	}
+	if err := mfs.Remove("out/z"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	if err := mfs.RemoveAll("out"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfs.Stat("out/z/b/c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected removed file not to exist, got %v", err)
+	}
+	f, err := mfs.Create("logs/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte("one\n"))
+	_, _ = f.Write([]byte("two\n"))
+	_ = f.Close()
+	if data, _ := mfs.ReadFile("logs/app.log"); string(data) != "one\ntwo\n" {
+		t.Errorf("Expected both writes, got %q", data)
+	}
+}
+
+func TestMemFS_NthWriteFault(t *testing.T) {
+	mfs := newTestMemFS(t)
+	mfs.InjectFault(testutil.FSFault{Op: testutil.FSOpWrite, Pattern: "*.db", Nth: 3, Err: syscall.ENOSPC})
+	f, err := mfs.Create("data/app.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		_, err = f.Write([]byte("x"))
+		if (i == 3) != errors.Is(err, syscall.ENOSPC) {
+			t.Errorf("Write %d: unexpected error %v", i, err)
+		}
+	}
+	if err := mfs.WriteFile("data/notes.txt", []byte("ok"), 0o644); err != nil {
+		t.Errorf("Expected writes to other files to succeed, got %v", err)
+	}
+}
+
+func TestMemFS_RenameFault(t *testing.T) {
+	mfs := newTestMemFS(t)
+	mfs.InjectFault(testutil.FSFault{Op: testutil.FSOpRename, Err: syscall.EXDEV})
+	err := mfs.Rename("data/app.db", "logs/app.db")
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) || !errors.Is(err, syscall.EXDEV) {
+		t.Fatalf("Expected an EXDEV *os.LinkError, got %v", err)
+	}
+	if _, err := mfs.Stat("data/app.db"); err != nil {
+		t.Errorf("Expected failed rename to leave the file, got %v", err)
+	}
+	mfs.ClearFaults()
+	if err := mfs.Rename("data/app.db", "logs/app.db"); err != nil {
+		t.Errorf("Expected rename to succeed once faults are cleared, got %v", err)
+	}
+}
+
+func TestMemFS_ReadOnlyDir(t *testing.T) {
+	mfs := newTestMemFS(t)
+	if err := mfs.Chmod("data", 0o555); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"create": mfs.WriteFile("data/new.txt", nil, 0o644),
+		"remove": mfs.Remove("data/notes.txt"),
+		"rename": mfs.Rename("data/notes.txt", "notes.txt"),
+		"mkdir":  mfs.Mkdir("data/sub", 0o755),
+	} {
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("Expected %s in a read-only directory to fail with permission denied, got %v", name, err)
+		}
+	}
+	if err := mfs.WriteFile("data/notes.txt", []byte("edited"), 0o644); err != nil {
+		t.Errorf("Expected existing writable files to stay writable, got %v", err)
+	}
+}
+
+func TestMemFS_RenameIntoItself(t *testing.T) {
+	mfs := newTestMemFS(t)
+	for _, newName := range []string{"data", "data/sub"} {
+		err := mfs.Rename("data", newName)
+		if !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("Expected renaming data to %s to fail with EINVAL, got %v", newName, err)
+		}
+	}
+	if err := fstest.TestFS(mfs, "data/app.db", "data/notes.txt"); err != nil {
+		t.Errorf("Expected tree to be intact after rejected renames: %v", err)
+	}
+}
+
+func TestMemFS_RenameOntoNonEmptyDir(t *testing.T) {
+	mfs := newTestMemFS(t)
+	_ = mfs.WriteFile("logs/app.log", []byte("log"), 0o644)
+	err := mfs.Rename("data", "logs")
+	if !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Fatalf("Expected ENOTEMPTY, got %v", err)
+	}
+	if _, err := mfs.Stat("logs/app.db"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected trees not to be merged, got %v", err)
+	}
+	_ = mfs.Remove("logs/app.log")
+	if err := mfs.Rename("data", "logs"); err != nil {
+		t.Fatalf("Expected rename onto an empty directory to succeed, got %v", err)
+	}
+	if err := fstest.TestFS(mfs, "logs/app.db", "logs/notes.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMemFS_MkdirAllRootedPath(t *testing.T) {
+	mfs := newTestMemFS(t)
+	for _, name := range []string{"/tmp/x", "/", "a/../b"} {
+		err := mfs.MkdirAll(name, 0o755)
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("Expected MkdirAll(%q) to fail with an invalid argument *fs.PathError, got %v", name, err)
+		}
+	}
+}
+
+func TestMemFS_RemoveAllRoot(t *testing.T) {
+	mfs := newTestMemFS(t)
+	if err := mfs.RemoveAll("."); !errors.Is(err, syscall.EINVAL) {
+		t.Fatalf("Expected RemoveAll(\".\") to fail with EINVAL, got %v", err)
+	}
+	if err := fstest.TestFS(mfs, "data/app.db", "data/notes.txt", "logs", "run.sh"); err != nil {
+		t.Errorf("Expected tree to be intact: %v", err)
+	}
+	if err := mfs.WriteFile("y.txt", []byte("y"), 0o644); err != nil {
+		t.Errorf("Expected the root to stay writable, got %v", err)
+	}
+}
+
+func TestMemFS_RenameTypeMismatch(t *testing.T) {
+	mfs := newTestMemFS(t)
+	if err := mfs.Rename("run.sh", "logs"); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("Expected renaming a file onto a directory to fail with EISDIR, got %v", err)
+	}
+	if err := mfs.Rename("logs", "run.sh"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("Expected renaming a directory onto a file to fail with ENOTDIR, got %v", err)
+	}
+}
+
+func TestMemFS_StatFault(t *testing.T) {
+	mfs := newTestMemFS(t)
+	mfs.InjectFault(testutil.FSFault{Op: testutil.FSOpStat, Pattern: "*.db", Err: syscall.EIO})
+	if _, err := mfs.Stat("data/app.db"); !errors.Is(err, syscall.EIO) {
+		t.Errorf("Expected Stat to fail with the injected fault, got %v", err)
+	}
+	if _, err := mfs.Stat("run.sh"); err != nil {
+		t.Errorf("Expected Stat of other files to succeed, got %v", err)
+	}
+}
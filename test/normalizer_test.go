@@ -0,0 +1,85 @@
+package test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestNormalizer_BuiltInRules(t *testing.T) {
+	n := testutil.NewNormalizer()
+	dir := t.TempDir()
+	home, _ := os.UserHomeDir()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"tmpdir", "wrote " + filepath.Join(dir, "out.txt"), "wrote <TMPDIR>/out.txt"},
+		{"home", "config at " + filepath.Join(home, ".apprc"), "config at <HOME>/.apprc"},
+		{"windows", `read C:\Users\alice\file.txt`, "read C:/Users/alice/file.txt"},
+		{"unc", `share \\server\share\x.txt`, "share //server/share/x.txt"},
+		{"json_escapes", `{"msg":"a\nb\nc","path":"\\tmp"}`, `{"msg":"a\nb\nc","path":"\\tmp"}`},
+		{"uuid", "id 123e4567-e89b-12d3-a456-426614174000 ok", "id <UUID> ok"},
+		{"timestamp", "at 2025-01-02T03:04:05.123Z and 2025-01-02 03:04:05", "at <TIME> and <TIME>"},
+		{"duration", "took 1.5s (2m3s, 250ms)", "took <DURATION> (<DURATION>, <DURATION>)"},
+		{"pid", "started pid=4242", "started pid=<PID>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := n.Normalize(tc.in); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+	t.Run("tmpdir_siblings", func(t *testing.T) {
+		tmp := filepath.ToSlash(os.TempDir())
+		for _, in := range []string{tmp + "foo/x", tmp + "/cache/x", filepath.ToSlash(dir) + "x/y"} {
+			if got := n.Normalize(in); got != in {
+				t.Errorf("Expected %q not to be normalized, got %q", in, got)
+			}
+		}
+	})
+	t.Run("subtest_tmpdir", func(t *testing.T) {
+		in := filepath.Join(t.TempDir(), "x")
+		if got := n.Normalize(in); got != "<TMPDIR>/x" {
+			t.Errorf("Expected a subtest's temp dir to be normalized, got %q", got)
+		}
+	})
+}
+
+func TestNormalizer_CustomRules(t *testing.T) {
+	n := testutil.NewNormalizer().
+		AddReplacer("build", regexp.MustCompile(`build [0-9a-f]{7}`), "build <SHA>").
+		AddLiteral("host", "db.internal:5432", "<DB>").
+		RemoveRule("duration")
+
+	got := n.Normalize("build abc1234 connected to db.internal:5432 in 5s")
+	want := "build <SHA> connected to <DB> in 5s"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNormalizer_WriterAndHandler(t *testing.T) {
+	n := testutil.NewNormalizer()
+	dir := t.TempDir()
+
+	w := testutil.NewBufferedWriter()
+	w.Printf("Saved %s in 12ms\n", dir)
+	if got := w.NormalizedStdout(n); got != "Saved <TMPDIR> in <DURATION>\n" {
+		t.Errorf("Expected normalized stdout, got %q", got)
+	}
+
+	h := testutil.NewBufferedLogHandler()
+	slog.New(h).Info("saved", "dir", dir)
+	entries := h.NormalizedEntries(n)
+	if len(entries) != 1 || entries[0].String() != "INFO: saved at <TIME> [dir=<TMPDIR>]" {
+		t.Errorf("Expected normalized log entry, got %v", entries)
+	}
+}
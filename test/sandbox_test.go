@@ -0,0 +1,82 @@
+package test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestSandbox(t *testing.T) {
+	t.Setenv("SANDBOXAPP_TOKEN", "real-token")
+	t.Setenv("SANDBOXAPP_PROFILE", "prod")
+	t.Setenv("SANDBOX_OTHER", "keep")
+	t.Setenv("SANDBOX_CLEARED", "gone")
+	home := os.Getenv("HOME")
+
+	t.Run("sandboxed", func(t *testing.T) {
+		sb := testutil.Sandbox(t, &testutil.SandboxArgs{
+			Clear:         []string{"SANDBOX_CLEARED"},
+			ClearPrefixes: []string{"SANDBOXAPP_"},
+			Env:           map[string]string{"SANDBOXAPP_PROFILE": "test"},
+			HomeFiles:     testutil.TreeFiles{".sandboxapprc": "color=never\n"},
+			ConfigFiles:   testutil.TreeFiles{"sandboxapp/config.json": `{"debug":true}`},
+		})
+		if got := os.Getenv("HOME"); got != string(sb.Home) {
+			t.Errorf("Expected HOME=%s, got %s", sb.Home, got)
+		}
+		if got := os.Getenv("XDG_CONFIG_HOME"); got != string(sb.ConfigHome) {
+			t.Errorf("Expected XDG_CONFIG_HOME=%s, got %s", sb.ConfigHome, got)
+		}
+		if _, ok := os.LookupEnv("SANDBOXAPP_TOKEN"); ok {
+			t.Error("Expected SANDBOXAPP_TOKEN to be unset")
+		}
+		if _, ok := os.LookupEnv("SANDBOX_CLEARED"); ok {
+			t.Error("Expected SANDBOX_CLEARED to be unset")
+		}
+		if got := os.Getenv("SANDBOX_OTHER"); got != "keep" {
+			t.Errorf("Expected SANDBOX_OTHER to be kept, got %q", got)
+		}
+		if got := os.Getenv("SANDBOXAPP_PROFILE"); got != "test" {
+			t.Errorf("Expected Env to override cleared variables, got %q", got)
+		}
+		if runtime.GOOS == "linux" {
+			dir, _ := os.UserConfigDir()
+			if dir != string(sb.ConfigHome) {
+				t.Errorf("Expected os.UserConfigDir to be %s, got %s", sb.ConfigHome, dir)
+			}
+		}
+		data := testutil.LoadFile(t, dt.FilepathJoin(sb.ConfigHome, "sandboxapp/config.json"), true)
+		if string(data) != `{"debug":true}` {
+			t.Errorf("Expected seeded config file, got %q", data)
+		}
+		data = testutil.LoadFile(t, dt.FilepathJoin(sb.Home, ".sandboxapprc"), true)
+		if string(data) != "color=never\n" {
+			t.Errorf("Expected seeded home file, got %q", data)
+		}
+	})
+
+	if got := os.Getenv("HOME"); got != home {
+		t.Errorf("Expected HOME to be restored to %s, got %s", home, got)
+	}
+	if got := os.Getenv("SANDBOXAPP_TOKEN"); got != "real-token" {
+		t.Errorf("Expected SANDBOXAPP_TOKEN to be restored, got %q", got)
+	}
+	if got := os.Getenv("SANDBOX_CLEARED"); got != "gone" {
+		t.Errorf("Expected SANDBOX_CLEARED to be restored, got %q", got)
+	}
+}
+
+func TestSandbox_RefusesParallel(t *testing.T) {
+	t.Run("parallel", func(t *testing.T) {
+		t.Parallel()
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected Sandbox to panic in a parallel test")
+			}
+		}()
+		testutil.Sandbox(t, nil)
+	})
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestBufferedWriter_ScreenProgressBar(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	w.Printf("Downloading\n")
+	for _, pct := range []int{10, 50, 100} {
+		w.Printf("\r[%-10s] %3d%%", strings.Repeat("#", pct/10), pct)
+	}
+	w.Printf("\n\x1b[32mdone\x1b[0m\n")
+
+	s := w.Screen(20, 4)
+	want := "Downloading\n[##########] 100%\ndone\n"
+	if s.String() != want {
+		t.Errorf("Expected screen:\n%s\ngot:\n%s", want, s.String())
+	}
+	frames := s.Frames()
+	if len(frames) != 5 {
+		t.Fatalf("Expected 5 frames, got %d", len(frames))
+	}
+	if frames[1][1] != "[#         ]  10%" {
+		t.Errorf("Expected second frame to show 10%%, got %q", frames[1][1])
+	}
+}
+
+func TestScreen_CursorAndErase(t *testing.T) {
+	s := testutil.NewScreen(10, 3)
+	_, _ = s.Write([]byte("line one\nline two\nspinner |"))
+	_, _ = s.Write([]byte("\b/\x1b[2A\x1b[2K\x1b[1Gtop"))
+	_, _ = s.Write([]byte("\x1b[3;1Hdone\x1b"))
+	_, _ = s.Write([]byte("[K"))
+
+	want := []string{"top", "line two", "done"}
+	if got := s.Lines(); !slices.Equal(got, want) {
+		t.Errorf("Expected lines %q, got %q", want, got)
+	}
+	if row, col := s.Cursor(); row != 2 || col != 4 {
+		t.Errorf("Expected cursor at 2,4, got %d,%d", row, col)
+	}
+}
+
+func TestScreen_WrapAndScroll(t *testing.T) {
+	s := testutil.NewScreen(4, 2)
+	_, _ = s.Write([]byte("abcdefgh\nij"))
+
+	want := []string{"efgh", "ij"}
+	if got := s.Lines(); !slices.Equal(got, want) {
+		t.Errorf("Expected lines %q, got %q", want, got)
+	}
+}
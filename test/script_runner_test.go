@@ -0,0 +1,37 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mikeschinkel/go-cliutil"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestRunScripts(t *testing.T) {
+	testutil.RunScripts(t, &testutil.ScriptArgs{
+		Dir: "testdata/scripts",
+		Commands: map[string]func() cliutil.Command{
+			"greet": newGreetCmd,
+			"cat":   newCatCmd,
+		},
+	})
+}
+
+func TestRunScript_ScopesEnvAndDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.RunScript(t, "testdata/scripts/greet.txtar", &testutil.ScriptArgs{
+		Commands: map[string]func() cliutil.Command{"greet": newGreetCmd},
+	})
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("Expected working directory %s after the script, got %s", wd, now)
+	}
+	for _, key := range []string{"WORK", "GREETING"} {
+		if value, ok := os.LookupEnv(key); ok {
+			t.Errorf("Expected %s to be unset after the script, got %q", key, value)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestScriptedInput_Conversation(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	in := testutil.NewScriptedInput(t, w).
+		Expect(`\[y/N\] $`, "y\n").
+		Expect(`name: $`, "Alice\n")
+
+	testutil.RunCLIWithArgs(t, newConfirmCmd(), &testutil.CLIRunArgs{
+		Writer: w,
+		Stdin:  in,
+	}).ExpectSuccess().ExpectStdoutContains("Deleted by Alice\n")
+
+	want := "Delete everything? [y/N] [sent \"y\\n\"]\nYour name: [sent \"Alice\\n\"]\n"
+	if in.Transcript() != want {
+		t.Errorf("Expected transcript %q, got %q", want, in.Transcript())
+	}
+}
+
+func TestScriptedInput_EOFAfterScript(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	in := testutil.NewScriptedInput(t, w).Expect(`\[y/N\]`, "n\n").AllowEOF()
+
+	testutil.RunCLIWithArgs(t, newConfirmCmd(), &testutil.CLIRunArgs{
+		Writer: w,
+		Stdin:  in,
+	}).ExpectSuccess().ExpectStdoutContains("Aborted")
+
+	n, err := in.Read(make([]byte, 8))
+	if n != 0 || err == nil {
+		t.Errorf("Expected EOF once every response was sent, got %d, %v", n, err)
+	}
+}
+
+func TestScriptedInput_FailsOnUnexpectedPrompt(t *testing.T) {
+	start := time.Now()
+	expectFailure(t, "TestScriptedInput_UnexpectedPromptFailing",
+		"unexpected prompt \"Delete everything? [y/N] \", expected `name: $`",
+		"--- transcript ---")
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Errorf("Expected an unexpected prompt to fail before the timeout, took %s", elapsed)
+	}
+}
+
+func TestScriptedInput_UnexpectedPromptFailing(t *testing.T) {
+	failingTest(t)
+	w := testutil.NewBufferedWriter()
+	in := testutil.NewScriptedInput(t, w).
+		SetTimeout(30*time.Second).
+		Expect(`name: $`, "Alice\n")
+	testutil.RunCLIWithArgs(t, newConfirmCmd(), &testutil.CLIRunArgs{Writer: w, Stdin: in})
+}
+
+func TestScriptedInput_FailsOnReadAfterScript(t *testing.T) {
+	expectFailure(t, "TestScriptedInput_ReadAfterScriptFailing",
+		"unexpected prompt \"Your name: \" after the script ended",
+		"Delete everything? [y/N] [sent \"y\\n\"]")
+}
+
+func TestScriptedInput_ReadAfterScriptFailing(t *testing.T) {
+	failingTest(t)
+	w := testutil.NewBufferedWriter()
+	in := testutil.NewScriptedInput(t, w).Expect(`\[y/N\] $`, "y\n")
+	testutil.RunCLIWithArgs(t, newConfirmCmd(), &testutil.CLIRunArgs{Writer: w, Stdin: in})
+}
+
+func TestScriptedInput_WriterReset(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	in := testutil.NewScriptedInput(t, w).
+		Expect(`first prompt: $`, "a\n").
+		Expect(`two: $`, "b\n")
+	buf := make([]byte, 8)
+
+	w.Printf("first prompt: ")
+	if n, err := in.Read(buf); err != nil || string(buf[:n]) != "a\n" {
+		t.Fatalf("Expected first response, got %q, %v", buf[:n], err)
+	}
+	w.Reset()
+	w.Printf("two: ")
+	if n, err := in.Read(buf); err != nil || string(buf[:n]) != "b\n" {
+		t.Errorf("Expected second response after Reset, got %q, %v", buf[:n], err)
+	}
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestCaptureStdio(t *testing.T) {
+	c := testutil.CaptureStdio(t)
+	fmt.Println("stray println")
+	fmt.Fprintf(os.Stderr, "stray warning\n")
+	c.Stop()
+
+	if c.GetStdout() != "stray println\n" {
+		t.Errorf("Expected captured stdout %q, got %q", "stray println\n", c.GetStdout())
+	}
+	if !c.ContainsStderr("stray warning") {
+		t.Errorf("Expected captured stderr to contain 'stray warning', got %q", c.GetStderr())
+	}
+	if len(c.Timeline()) != 2 {
+		t.Errorf("Expected 2 timeline entries, got %v", c.Timeline())
+	}
+}
+
+func TestCaptureStdio_RestoresAfterStop(t *testing.T) {
+	first := testutil.CaptureStdio(t)
+	first.Stop()
+
+	second := testutil.CaptureStdio(t)
+	fmt.Print("second")
+	second.Stop()
+	second.Stop()
+
+	if first.GetStdout() != "" {
+		t.Errorf("Expected nothing captured after Stop, got %q", first.GetStdout())
+	}
+	if second.GetStdout() != "second" {
+		t.Errorf("Expected second capture to contain %q, got %q", "second", second.GetStdout())
+	}
+}
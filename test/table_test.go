@@ -0,0 +1,99 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestParseTable_Tabwriter(t *testing.T) {
+	w := testutil.NewBufferedWriter()
+	tw := tabwriter.NewWriter(w.Writer(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tSTATUS\tLAST SEEN")
+	_, _ = fmt.Fprintln(tw, "api-server\tok\t2m")
+	_, _ = fmt.Fprintln(tw, "worker\tdegraded\t")
+	_, _ = fmt.Fprintln(tw, "db\tok\t1h")
+	_ = tw.Flush()
+
+	table, err := testutil.ParseTable(w.GetStdout())
+	if err != nil {
+		t.Fatalf("Failed to parse table: %v", err)
+	}
+	if !slices.Equal(table.Header, []string{"NAME", "STATUS", "LAST SEEN"}) {
+		t.Errorf("Expected header [NAME STATUS LAST SEEN], got %q", table.Header)
+	}
+	table.ExpectRowCount(t, 3).
+		ExpectCell(t, "NAME", "worker", "STATUS", "degraded").
+		ExpectCell(t, "NAME", "worker", "LAST SEEN", "").
+		ExpectCell(t, "name", "db", "last seen", "1h")
+
+	if got := table.Values("STATUS"); !slices.Equal(got, []string{"ok", "degraded", "ok"}) {
+		t.Errorf("Expected STATUS values [ok degraded ok], got %q", got)
+	}
+	if rows := table.Where("STATUS", "ok"); len(rows) != 2 || rows[1].Get("NAME") != "db" {
+		t.Errorf("Expected two ok rows ending with db, got %v", rows)
+	}
+}
+
+func TestParseTable_PaddingChanges(t *testing.T) {
+	narrow, _ := testutil.ParseTable("NAME  STATUS\n----  ------\nfoo   ok\n")
+	wide, _ := testutil.ParseTable("\nNAME        STATUS\nfoo         ok\n\ntrailing text\n")
+
+	if narrow.String() != wide.String() {
+		t.Errorf("Expected padding to not matter, got %q and %q", narrow, wide)
+	}
+}
+
+func TestParseTable_Markdown(t *testing.T) {
+	table, err := testutil.ParseTable("| Name | Status |\n|------|:------:|\n| foo  | ok     |\n| bar  | failed |\n")
+	if err != nil {
+		t.Fatalf("Failed to parse table: %v", err)
+	}
+	table.ExpectRowCount(t, 2).ExpectCell(t, "Name", "bar", "Status", "failed")
+
+	_, err = testutil.ParseTable("| a | b |\n| 1 |\n")
+	if err == nil {
+		t.Error("Expected an error for a row with too few cells")
+	}
+	_, err = testutil.ParseTable("\n\n")
+	if !errors.Is(err, testutil.ErrNoTable) {
+		t.Errorf("Expected ErrNoTable, got %v", err)
+	}
+}
+
+func TestParseTable_TabSeparatedBlankCell(t *testing.T) {
+	table, err := testutil.ParseTable("NAME\tOWNER\tSTATUS\napi\t\tok\ndb\tops\t\n")
+	if err != nil {
+		t.Fatalf("Failed to parse table: %v", err)
+	}
+	table.ExpectRowCount(t, 2).
+		ExpectCell(t, "NAME", "api", "OWNER", "").
+		ExpectCell(t, "NAME", "api", "STATUS", "ok").
+		ExpectCell(t, "NAME", "db", "OWNER", "ops").
+		ExpectCell(t, "NAME", "db", "STATUS", "")
+}
+
+func TestParseTable_PlaceholderRows(t *testing.T) {
+	table, err := testutil.ParseTable("NAME  OWNER  TAGS\n----  -----  ----\nfoo   -      -\nbar   --     --\nbaz   ---    ---\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 3 {
+		t.Fatalf("Expected placeholder rows to be kept, got %q", table.Rows)
+	}
+	if got := table.Rows[1]; got[1] != "--" || got[2] != "--" {
+		t.Errorf("Expected bar's cells to be placeholders, got %q", got)
+	}
+
+	table, err = testutil.ParseTable("+------+-----+\n| Name | Age |\n+------+-----+\n| -    | -   |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 1 || table.Header[0] != "Name" {
+		t.Errorf("Expected rules above and below the header to be skipped, got %q / %q", table.Header, table.Rows)
+	}
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestBufferedLogHandler_ForTest(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler()
+	slog.New(handler).Info("Parent message")
+
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			logger := slog.New(handler.ForTest(t))
+			for i := 0; i < 3; i++ {
+				logger.Info(fmt.Sprintf("%s message %d", name, i))
+			}
+
+			entries := handler.EntriesFor(t)
+			if len(entries) != 3 {
+				t.Fatalf("Expected 3 entries for %s, got %d", name, len(entries))
+			}
+			for _, entry := range entries {
+				if entry.Test != t.Name() {
+					t.Errorf("Expected entry tagged %q, got %q", t.Name(), entry.Test)
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedLogHandler_ContextWithTest(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler()
+	logger := slog.New(handler)
+
+	t.Run("sub", func(t *testing.T) {
+		logger.InfoContext(testutil.ContextWithTest(context.Background(), t), "Tagged by context")
+	})
+	logger.Info("Untagged")
+
+	entries := handler.EntriesFor(t)
+	if len(entries) != 1 || entries[0].Message != "Tagged by context" {
+		t.Errorf("Expected parent to see only the subtest's tagged entry, got %v", entries)
+	}
+	if !handler.Contains(`"test":"TestBufferedLogHandler_ContextWithTest/sub"`) {
+		t.Errorf("Expected test name in buffer, got %q", handler.String())
+	}
+}
+
+func TestBufferedLogHandler_ForTestWithAttrs(t *testing.T) {
+	handler := testutil.NewBufferedLogHandler()
+
+	t.Run("sub", func(t *testing.T) {
+		logger := slog.New(handler.ForTest(t)).With("request_id", "abc").WithGroup("http")
+		logger.Info("Handled", "status", 200)
+
+		entries := handler.EntriesFor(t)
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 entry, got %d", len(entries))
+		}
+		if !slices.Equal(entries[0].Attrs, []string{"request_id=abc", "http=[status=200]"}) {
+			t.Errorf("Expected attrs from With and WithGroup, got %q", entries[0].Attrs)
+		}
+	})
+}
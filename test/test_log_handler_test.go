@@ -0,0 +1,70 @@
+package test
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestTestLogHandler_Basic(t *testing.T) {
+	out, err := runChildTest("TestTestLogHandler_BasicLogging")
+	if err != nil {
+		t.Fatalf("Expected TestTestLogHandler_BasicLogging to pass, got %v:\n%s", err, out)
+	}
+	for _, re := range []string{
+		`(?m)^\s+test_log_handler_test\.go:\d+: \[TestTestLogHandler_BasicLogging\] INFO Visible in go test -v output user=alice$`,
+		`(?m)^\s+test_log_handler_test\.go:\d+: \[TestTestLogHandler_BasicLogging\] DEBUG Grouped request_id=abc http\.status=200$`,
+	} {
+		if !regexp.MustCompile(re).MatchString(out) {
+			t.Errorf("Expected output to match %#q, got:\n%s", re, out)
+		}
+	}
+}
+
+func TestTestLogHandler_BasicLogging(t *testing.T) {
+	childOnly(t)
+	logger := testutil.NewTestLogger(t)
+
+	logger.Info("Visible in go test -v output", slog.String("user", "alice"))
+	logger.With("request_id", "abc").WithGroup("http").Debug("Grouped", slog.Int("status", 200))
+}
+
+func TestTestLogHandler_SlogInterface(t *testing.T) {
+	var handler slog.Handler = testutil.NewTestLogHandler(t)
+
+	if !handler.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Handler should be enabled for all levels")
+	}
+	if handler.WithGroup("") != handler {
+		t.Error("WithGroup with an empty name should return the same handler")
+	}
+	if handler.WithAttrs([]slog.Attr{slog.String("key", "value")}) == nil {
+		t.Error("WithAttrs should return a non-nil handler")
+	}
+}
+
+func TestTestLogHandler_AfterTestFinished(t *testing.T) {
+	var handler *testutil.TestLogHandler
+	var logger *slog.Logger
+
+	t.Run("subtest", func(t *testing.T) {
+		handler = testutil.NewTestLogHandler(t)
+		logger = slog.New(handler)
+		logger.Info("During subtest")
+	})
+
+	// Would panic with "Log in goroutine after Test has completed" if written
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("After subtest finished")
+	}()
+	<-done
+
+	if handler.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped record, got %d", handler.Dropped())
+	}
+}
@@ -0,0 +1,38 @@
+// Command exitcmd is built by the binary harness tests. It echoes stdin,
+// writes to stderr and exits with the code given by its first argument, waits
+// for SIGINT when given "wait", or asks for a name when given "prompt".
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "wait" {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt)
+		fmt.Println("waiting")
+		<-sigs
+		fmt.Println("interrupted")
+		os.Exit(130)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "prompt" {
+		fmt.Print("Name? ")
+		name, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		fmt.Printf("Hi %s", name)
+		return
+	}
+	data, _ := io.ReadAll(os.Stdin)
+	fmt.Printf("stdin: %s\n", data)
+	fmt.Fprintf(os.Stderr, "mode: %s\n", os.Getenv("EXITCMD_MODE"))
+	code := 0
+	if len(os.Args) > 1 {
+		code, _ = strconv.Atoi(os.Args[1])
+	}
+	os.Exit(code)
+}
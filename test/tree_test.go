@@ -0,0 +1,70 @@
+package test
+
+import (
+	"embed"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-testutil"
+)
+
+//go:embed testdata/tree
+var treeFS embed.FS
+
+func TestMakeTree(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	root := testutil.MakeTree(t, testutil.Tree{
+		"bin/run":        testutil.TreeFile("#!/bin/sh\n").WithMode(0o755),
+		"docs/README.md": testutil.TreeFile("# Docs\n").WithModTime(modTime),
+		"docs":           testutil.TreeDir().WithModTime(modTime),
+		"current":        testutil.TreeSymlink("bin"),
+		"cache/":         testutil.TreeDir(),
+		"locked":         testutil.TreeDir().WithMode(0o555),
+	})
+
+	data := testutil.LoadFile(t, dt.FilepathJoin(root, "docs/README.md"), true)
+	if string(data) != "# Docs\n" {
+		t.Errorf("Expected README content, got %q", data)
+	}
+	info, err := os.Stat(string(root) + "/bin/run")
+	if err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("Expected bin/run with mode 0755, got %v, %v", info, err)
+	}
+	for _, name := range []string{"docs", "docs/README.md"} {
+		info, err = os.Stat(string(root) + "/" + name)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			t.Errorf("Expected %s modified at %v, got %v, %v", name, modTime, info, err)
+		}
+	}
+	target, err := os.Readlink(string(root) + "/current")
+	if err != nil || target != "bin" {
+		t.Errorf("Expected current to link to bin, got %q, %v", target, err)
+	}
+	info, err = os.Stat(string(root) + "/cache")
+	if err != nil || !info.IsDir() {
+		t.Errorf("Expected cache directory, got %v, %v", info, err)
+	}
+	info, err = os.Stat(string(root) + "/locked")
+	if err != nil || !info.IsDir() || info.Mode().Perm() != 0o555 {
+		t.Errorf("Expected locked directory with mode 0555, got %v, %v", info, err)
+	}
+}
+
+func TestMakeTree_TxtarAndFS(t *testing.T) {
+	root := testutil.MakeTree(t, testutil.ParseTxtar([]byte("-- a/b.txt --\nfrom txtar\n")))
+	if data := testutil.LoadFile(t, dt.FilepathJoin(root, "a/b.txt"), true); string(data) != "from txtar\n" {
+		t.Errorf("Expected txtar file content, got %q", data)
+	}
+
+	root = testutil.MakeTree(t, testutil.TreeFromFS(treeFS, "testdata/tree"))
+	if data := testutil.LoadFile(t, dt.FilepathJoin(root, "sub/b.txt"), true); string(data) != "nested\n" {
+		t.Errorf("Expected embedded file content, got %q", data)
+	}
+
+	root = testutil.MakeTree(t, testutil.TreeFiles{"x.txt": "x", "empty/": ""})
+	if data := testutil.LoadFile(t, dt.FilepathJoin(root, "x.txt"), true); string(data) != "x" {
+		t.Errorf("Expected x.txt content, got %q", data)
+	}
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mikeschinkel/go-testutil"
+)
+
+func TestParseTxtar(t *testing.T) {
+	a := testutil.ParseTxtar([]byte("comment\n-- a.txt --\nhello\n-- dir/b.txt --\nno newline"))
+
+	if string(a.Comment) != "comment\n" {
+		t.Errorf("Expected comment %q, got %q", "comment\n", a.Comment)
+	}
+	if len(a.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(a.Files))
+	}
+	f, ok := a.File("dir/b.txt")
+	if !ok || string(f.Data) != "no newline\n" {
+		t.Errorf("Expected dir/b.txt with trailing newline added, got %q", f.Data)
+	}
+	if _, ok := a.File("missing"); ok {
+		t.Error("Expected missing file to not be found")
+	}
+}
+
+func TestTxtarArchive_FormatRoundTrip(t *testing.T) {
+	want := "comment\n-- a.txt --\nhello\n-- empty --\n-- b.txt --\nworld\n"
+	got := string(testutil.ParseTxtar([]byte(want)).Format())
+	if got != want {
+		t.Errorf("Expected round trip to return %q, got %q", want, got)
+	}
+}
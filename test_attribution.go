@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type testNameKey struct{}
+
+// ContextWithTest returns a context that tags records logged with it, e.g. via
+// logger.InfoContext, with the name of t so they can be queried with
+// BufferedLogHandler.EntriesFor
+func ContextWithTest(ctx context.Context, t *testing.T) context.Context {
+	return context.WithValue(ctx, testNameKey{}, t.Name())
+}
+
+// testFromContext returns the test name stored by ContextWithTest, if any
+func testFromContext(ctx context.Context) (name string) {
+	if ctx == nil {
+		goto end
+	}
+	name, _ = ctx.Value(testNameKey{}).(string)
+end:
+	return name
+}
+
+// ForTest returns a handler that captures into h while tagging every record
+// with the name of t. Parallel subtests can each derive their own handler from
+// a shared BufferedLogHandler and query only their own entries with EntriesFor.
+func (h *BufferedLogHandler) ForTest(t *testing.T) slog.Handler {
+	return newBoundLogHandler(h, t.Name())
+}
+
+// EntriesFor returns the captured entries tagged with the name of t or the
+// name of any of its subtests
+func (h *BufferedLogHandler) EntriesFor(t *testing.T) (entries LogEntries) {
+	name := t.Name()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, entry := range h.entries() {
+		if entry.Test != name && !strings.HasPrefix(entry.Test, name+"/") {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,166 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestLogHandler implements slog.Handler by writing each record to the test's
+// output so logs appear beside the test that produced them in go test -v.
+//
+// Each line is prefixed with the file:line of the log call and the test name.
+// Lines are written with t.Output rather than t.Log, and the location is taken
+// from the record's source, because t.Log would report this file and t.Helper
+// cannot mark the log/slog frames between the call site and the handler.
+//
+// Records handled after the test has finished are dropped rather than written
+// so late goroutine logs cannot panic the test binary.
+type TestLogHandler struct {
+	state *testLogState
+	ops   []testLogOp
+}
+
+// testLogOp is a WithAttrs or WithGroup call to replay when formatting
+type testLogOp struct {
+	group string
+	attrs []slog.Attr
+}
+
+// testLogState is shared by a TestLogHandler and the handlers derived from it
+type testLogState struct {
+	t       *testing.T
+	mu      sync.Mutex
+	done    bool
+	dropped int
+}
+
+var _ slog.Handler = (*TestLogHandler)(nil)
+
+// NewTestLogHandler creates a TestLogHandler that writes to t
+func NewTestLogHandler(t *testing.T) *TestLogHandler {
+	state := &testLogState{t: t}
+	t.Cleanup(func() {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		state.done = true
+	})
+	return &TestLogHandler{state: state}
+}
+
+// NewTestLogger creates a logger that writes to t using a TestLogHandler
+func NewTestLogger(t *testing.T) *slog.Logger {
+	return slog.New(NewTestLogHandler(t))
+}
+
+// Enabled implements slog.Handler
+func (h *TestLogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler
+func (h *TestLogHandler) Handle(ctx context.Context, r slog.Record) (err error) {
+	var buf bytes.Buffer
+	var sb strings.Builder
+	var th slog.Handler
+
+	th = slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level:       slog.Level(math.MinInt),
+		ReplaceAttr: dropBuiltinAttrs,
+	})
+	for _, op := range h.ops {
+		if op.group != "" {
+			th = th.WithGroup(op.group)
+			continue
+		}
+		th = th.WithAttrs(op.attrs)
+	}
+	err = th.Handle(ctx, r)
+	if err != nil {
+		goto end
+	}
+
+	if src := recordSource(r); src != "" {
+		sb.WriteString(src)
+		sb.WriteString(": ")
+	}
+	fmt.Fprintf(&sb, "[%s] %s %s", h.state.t.Name(), r.Level, r.Message)
+	if attrs := strings.TrimSpace(buf.String()); attrs != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(attrs)
+	}
+	sb.WriteByte('\n')
+	h.state.write(sb.String())
+
+end:
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *TestLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(testLogOp{attrs: attrs})
+}
+
+// WithGroup implements slog.Handler
+func (h *TestLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(testLogOp{group: name})
+}
+
+func (h *TestLogHandler) with(op testLogOp) *TestLogHandler {
+	ops := make([]testLogOp, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &TestLogHandler{
+		state: h.state,
+		ops:   append(ops, op),
+	}
+}
+
+// Dropped returns the number of records dropped because they were handled
+// after the test finished
+func (h *TestLogHandler) Dropped() int {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	return h.state.dropped
+}
+
+func (s *testLogState) write(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		s.dropped++
+		return
+	}
+	_, _ = s.t.Output().Write([]byte(line))
+}
+
+// dropBuiltinAttrs removes the attributes TestLogHandler formats itself
+func dropBuiltinAttrs(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) != 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey:
+		return slog.Attr{}
+	}
+	return a
+}
+
+// recordSource returns "file.go:line" for the record's call site, or an empty
+// string if the record has no PC
+func recordSource(r slog.Record) string {
+	if r.PC == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+	return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+}
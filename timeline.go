@@ -0,0 +1,69 @@
+package testutil
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Streams that captured output is recorded on
+const (
+	StdoutStream = "stdout"
+	StderrStream = "stderr"
+	LogStream    = "log"
+)
+
+// TimelineEntry is a single captured write or log record. Seq orders entries
+// across every BufferedWriter and BufferedLogHandler in the test binary so
+// output and logs can be interleaved.
+type TimelineEntry struct {
+	Seq    uint64
+	Stream string
+	Text   string
+}
+
+// Timeline is a sequence of captured writes and log records
+type Timeline []TimelineEntry
+
+// captureSeq is shared by all captures so their entries can be merged in order
+var captureSeq atomic.Uint64
+
+func nextCaptureSeq() uint64 {
+	return captureSeq.Add(1)
+}
+
+// MergeTimelines merges timelines into a single timeline ordered by Seq
+func MergeTimelines(timelines ...Timeline) (merged Timeline) {
+	for _, tl := range timelines {
+		merged = append(merged, tl...)
+	}
+	slices.SortStableFunc(merged, func(a, b TimelineEntry) int {
+		switch {
+		case a.Seq < b.Seq:
+			return -1
+		case a.Seq > b.Seq:
+			return 1
+		}
+		return 0
+	})
+	return merged
+}
+
+// String returns the timeline with each entry's text prefixed by its stream
+func (tl Timeline) String() string {
+	var sb strings.Builder
+	for _, e := range tl {
+		for _, line := range strings.SplitAfter(e.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			sb.WriteString(e.Stream)
+			sb.WriteString(": ")
+			sb.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				sb.WriteByte('\n')
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,282 @@
+package testutil
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// TreeEntry describes a file, directory or symlink for MakeTree
+type TreeEntry struct {
+	Content string
+	Dir     bool
+	// Symlink is the link's target; a symlink has no content or mode
+	Symlink string
+	// Mode defaults to 0o644 for files and 0o755 for directories
+	Mode fs.FileMode
+	// ModTime is left as the time of creation when zero
+	ModTime time.Time
+}
+
+// TreeFile returns an entry for a file with content
+func TreeFile(content string) TreeEntry {
+	return TreeEntry{Content: content}
+}
+
+// TreeDir returns an entry for a directory, which is only needed for
+// directories that hold no other entries or need a mode or modification time
+func TreeDir() TreeEntry {
+	return TreeEntry{Dir: true}
+}
+
+// TreeSymlink returns an entry for a symlink to target
+func TreeSymlink(target string) TreeEntry {
+	return TreeEntry{Symlink: target}
+}
+
+// WithMode returns the entry with its mode set
+func (e TreeEntry) WithMode(mode fs.FileMode) TreeEntry {
+	e.Mode = mode
+	return e
+}
+
+// WithModTime returns the entry with its modification time set
+func (e TreeEntry) WithModTime(t time.Time) TreeEntry {
+	e.ModTime = t
+	return e
+}
+
+// TreeSpec is a description of a directory tree: a Tree, TreeFiles,
+// *TxtarArchive or the result of TreeFromFS
+type TreeSpec interface {
+	treeEntries() (map[string]TreeEntry, error)
+}
+
+// Tree maps slash-separated paths to entries, e.g.:
+//
+//	testutil.Tree{
+//		"bin/run":  testutil.TreeFile("#!/bin/sh\n").WithMode(0o755),
+//		"current":  testutil.TreeSymlink("bin"),
+//		"cache/":   testutil.TreeDir(),
+//	}
+type Tree map[string]TreeEntry
+
+func (tr Tree) treeEntries() (map[string]TreeEntry, error) {
+	return tr, nil
+}
+
+// TreeFiles maps slash-separated paths to file contents; a path ending in "/"
+// is an empty directory
+type TreeFiles map[string]string
+
+func (tf TreeFiles) treeEntries() (map[string]TreeEntry, error) {
+	entries := make(map[string]TreeEntry, len(tf))
+	for name, content := range tf {
+		if strings.HasSuffix(name, "/") {
+			entries[name] = TreeDir()
+			continue
+		}
+		entries[name] = TreeFile(content)
+	}
+	return entries, nil
+}
+
+func (a *TxtarArchive) treeEntries() (map[string]TreeEntry, error) {
+	entries := make(map[string]TreeEntry, len(a.Files))
+	for _, f := range a.Files {
+		entries[f.Name] = TreeFile(string(f.Data))
+	}
+	return entries, nil
+}
+
+// fsTree is the subtree of an fs.FS, such as an embed.FS
+type fsTree struct {
+	fsys fs.FS
+	dir  string
+}
+
+// TreeFromFS returns the subtree of fsys rooted at dir, e.g. an embed.FS
+// directory of fixtures. Modes are kept for fs.FS implementations that report
+// them; embed.FS files are read-only so they are written with default modes.
+func TreeFromFS(fsys fs.FS, dir string) TreeSpec {
+	return fsTree{fsys: fsys, dir: dir}
+}
+
+func (ft fsTree) treeEntries() (entries map[string]TreeEntry, err error) {
+	var sub fs.FS
+
+	sub, err = fs.Sub(ft.fsys, ft.dir)
+	if err != nil {
+		goto end
+	}
+	entries = make(map[string]TreeEntry)
+	err = fs.WalkDir(sub, ".", func(name string, d fs.DirEntry, err error) error {
+		var data []byte
+		var info fs.FileInfo
+
+		if err != nil || name == "." {
+			return err
+		}
+		info, err = d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			entries[name] = TreeEntry{Dir: true}
+			return nil
+		}
+		data, err = fs.ReadFile(sub, name)
+		if err != nil {
+			return err
+		}
+		entry := TreeFile(string(data))
+		if perm := info.Mode().Perm(); perm&0o200 != 0 {
+			entry.Mode = perm
+		}
+		entries[name] = entry
+		return nil
+	})
+end:
+	return entries, err
+}
+
+// MakeTree builds spec under a new t.TempDir() and returns its root
+func MakeTree(t *testing.T, spec TreeSpec) dt.DirPath {
+	t.Helper()
+	root := dt.DirPath(t.TempDir())
+	WriteTree(t, root, spec)
+	return root
+}
+
+// WriteTree builds spec under root, which need not exist yet. Directories are
+// created first, then files, then symlinks. Directory modes and modification
+// times are set last so they are not disturbed by writing the rest of the
+// tree. Symlinks keep the time they were created.
+func WriteTree(t *testing.T, root dt.DirPath, spec TreeSpec) {
+	var entries map[string]TreeEntry
+	var names []string
+	var err error
+
+	t.Helper()
+	entries, err = spec.treeEntries()
+	if err != nil {
+		t.Fatalf("Failed to read tree spec: %v", err)
+	}
+	err = root.MkdirAll(0o755)
+	if err != nil {
+		t.Fatalf("Failed to create tree root %s: %v", root, err)
+	}
+	for name := range entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, pass := range []func(dt.Filepath, TreeEntry) error{writeTreeDir, writeTreeFile, writeTreeSymlink} {
+		for _, name := range names {
+			file, err := treePath(root, name)
+			if err == nil {
+				err = pass(file, entries[name])
+			}
+			if err != nil {
+				t.Fatalf("Failed to create %s in tree: %v", name, err)
+			}
+		}
+	}
+	// Deepest first so finishing an entry does not change its directory
+	slices.Reverse(names)
+	for _, name := range names {
+		file, _ := treePath(root, name)
+		entry := entries[name]
+		err = finishTreeEntry(file, entry)
+		if err != nil {
+			t.Fatalf("Failed to set mode or modification time of %s in tree: %v", name, err)
+		}
+		if entry.Dir && entry.Mode != 0 && entry.Mode&0o200 == 0 {
+			// Lets the temp directory cleanup remove the directory's contents
+			t.Cleanup(func() { _ = os.Chmod(string(file), 0o755) })
+		}
+	}
+}
+
+// treePath returns the path of name under root, rejecting names that would
+// escape it
+func treePath(root dt.DirPath, name string) (file dt.Filepath, err error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean == "." {
+		err = fmt.Errorf("invalid tree path %q", name)
+		goto end
+	}
+	file = dt.FilepathJoin(root, clean)
+end:
+	return file, err
+}
+
+func writeTreeDir(file dt.Filepath, e TreeEntry) (err error) {
+	if e.Dir {
+		err = dt.DirPath(file).MkdirAll(0o755)
+	}
+	return err
+}
+
+// finishTreeEntry sets a directory's mode, which could otherwise prevent
+// writing its contents, and the entry's modification time
+func finishTreeEntry(file dt.Filepath, e TreeEntry) (err error) {
+	if e.Symlink != "" {
+		goto end
+	}
+	if e.Dir && e.Mode != 0 {
+		err = os.Chmod(string(file), e.Mode.Perm())
+		if err != nil {
+			goto end
+		}
+	}
+	if !e.ModTime.IsZero() {
+		err = os.Chtimes(string(file), e.ModTime, e.ModTime)
+	}
+end:
+	return err
+}
+
+func writeTreeFile(file dt.Filepath, e TreeEntry) (err error) {
+	var mode fs.FileMode
+
+	if e.Dir || e.Symlink != "" {
+		goto end
+	}
+	err = file.Dir().MkdirAll(0o755)
+	if err != nil {
+		goto end
+	}
+	mode = e.Mode.Perm()
+	if mode == 0 {
+		mode = 0o644
+	}
+	err = file.WriteFile([]byte(e.Content), mode)
+	if err != nil {
+		goto end
+	}
+	// WriteFile's mode is subject to the umask
+	err = os.Chmod(string(file), mode)
+end:
+	return err
+}
+
+func writeTreeSymlink(file dt.Filepath, e TreeEntry) (err error) {
+	if e.Symlink == "" {
+		goto end
+	}
+	err = file.Dir().MkdirAll(0o755)
+	if err != nil {
+		goto end
+	}
+	err = os.Symlink(e.Symlink, string(file))
+end:
+	return err
+}
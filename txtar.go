@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"bytes"
+	"strings"
+)
+
+// TxtarArchive is a txtar archive: a comment followed by files that are each
+// introduced by a "-- name --" marker line, the format the Go toolchain uses
+// for its script tests.
+type TxtarArchive struct {
+	Comment []byte
+	Files   []TxtarFile
+}
+
+// TxtarFile is a single file in a TxtarArchive
+type TxtarFile struct {
+	Name string
+	Data []byte
+}
+
+// ParseTxtar parses data as a txtar archive. Every file's data ends in a
+// newline unless it is empty.
+func ParseTxtar(data []byte) *TxtarArchive {
+	var name string
+
+	a := &TxtarArchive{}
+	a.Comment, name, data = findTxtarMarker(data)
+	for name != "" {
+		f := TxtarFile{Name: name}
+		f.Data, name, data = findTxtarMarker(data)
+		a.Files = append(a.Files, f)
+	}
+	return a
+}
+
+// findTxtarMarker returns the data before the next marker line, the name in
+// that marker and the data after it. name is empty if there are no more markers.
+func findTxtarMarker(data []byte) (before []byte, name string, after []byte) {
+	var i int
+	for {
+		if name, after = parseTxtarMarker(data[i:]); name != "" {
+			return fixTxtarNL(data[:i]), name, after
+		}
+		j := bytes.IndexByte(data[i:], '\n')
+		if j < 0 {
+			return fixTxtarNL(data), "", nil
+		}
+		i += j + 1
+	}
+}
+
+// parseTxtarMarker returns the file name and remaining data if data begins
+// with a marker line
+func parseTxtarMarker(data []byte) (name string, after []byte) {
+	if !bytes.HasPrefix(data, []byte("-- ")) {
+		return "", nil
+	}
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line, after = data[:i], data[i+1:]
+	}
+	line = bytes.TrimRight(line, "\r")
+	if !bytes.HasSuffix(line, []byte(" --")) || len(line) < len("-- x --") {
+		return "", nil
+	}
+	return strings.TrimSpace(string(line[3 : len(line)-3])), after
+}
+
+func fixTxtarNL(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	return append(bytes.Clone(data), '\n')
+}
+
+// Format returns the archive in txtar format
+func (a *TxtarArchive) Format() []byte {
+	var buf bytes.Buffer
+	buf.Write(fixTxtarNL(a.Comment))
+	for _, f := range a.Files {
+		buf.WriteString("-- " + f.Name + " --\n")
+		buf.Write(fixTxtarNL(f.Data))
+	}
+	return buf.Bytes()
+}
+
+// File returns the named file from the archive
+func (a *TxtarArchive) File(name string) (TxtarFile, bool) {
+	for _, f := range a.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return TxtarFile{}, false
+}